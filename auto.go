@@ -4,10 +4,34 @@ package cli
 AutoCobra sets up a common pattern for apps:
 commands are built into a tree of cobra subcommands,
 flags are created using pflag, options are loaded
-from env. vars, flags, and YAML config files.
+from flags, env. vars, and layered config files
+(see DefaultLayers), in that order of precedence.
+A "dump-config" command is added, which writes
+the effective configuration of a command, a "completion"
+command, which writes shell completion scripts, and a hidden
+"docs" command, which writes reference documentation.
+These aren't added if one of the specs has the same name,
+e.g. a command function named Docs.
+
+The returned error may be passed to Exit, which exits
+with a code based on the type of error, see ExitCode.
 */
 func AutoCobra(appname string, specs []Spec) error {
-	b := Cobra{}
+	b := NewAutoCobra(appname, specs, func(flags Provider) []Provider {
+		return autoProviders(appname, flags)
+	})
+	return b.Execute()
+}
+
+/*
+NewAutoCobra builds the commands the same way as AutoCobra, but options
+are loaded from the providers returned by "providers", which is called
+for each command with the provider of the command's flags. This is useful
+for loading options from somewhere other than the process environment
+and file system, e.g. in tests (see the clitest package).
+*/
+func NewAutoCobra(appname string, specs []Spec, providers func(flags Provider) []Provider) *Cobra {
+	b := &Cobra{}
 	b.Use = appname
 	b.SilenceUsage = true
 
@@ -16,13 +40,22 @@ func AutoCobra(appname string, specs []Spec) error {
 		opts := spec.Cmd().Opts
 		flags := PFlags(cmd.Flags(), opts, DotKey)
 
-		l := NewLoader(opts,
-			Env(appname),
-			flags,
-			YAML(DefaultYAML),
-		)
+		l := NewLoader(opts, providers(flags)...)
 		b.SetRunner(cmd, spec, l)
 	}
+	b.AddDumpConfig()
+	b.AddDocs()
+	b.AddCompletion()
+	return b
+}
 
-	return b.Execute()
+// autoProviders returns the providers AutoCobra uses to load
+// the options of a command, from highest to lowest precedence.
+// The first provider to set an option wins.
+func autoProviders(appname string, flags Provider) []Provider {
+	return []Provider{
+		flags,
+		Env(appname),
+		Layered(DefaultLayers(appname)),
+	}
 }
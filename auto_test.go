@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"github.com/spf13/pflag"
+	"os"
+	"testing"
+)
+
+func TestAutoPrecedence(t *testing.T) {
+	os.Setenv("AUTO_NAME", "env")
+	defer os.Unsetenv("AUTO_NAME")
+
+	tests := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"--name", "flag"}, "flag"},
+		{nil, "env"},
+	}
+	for _, test := range tests {
+		name := "default"
+		opts := []*Opt{
+			{Key: []string{"name"}, Value: &name, DefaultValue: name},
+		}
+		fs := pflag.NewFlagSet("auto", pflag.ContinueOnError)
+		flags := PFlags(fs, opts, DotKey)
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+
+		l := NewLoader(opts, autoProviders("auto", flags)...)
+		l.Load()
+		if errs := l.Errors(); errs != nil {
+			t.Fatal(errs)
+		}
+		if name != test.expect {
+			t.Errorf("%v: expected name to be %q, got %q", test.args, test.expect, name)
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 )
 
@@ -10,11 +14,26 @@ type ErrFatal struct {
 	error
 }
 
+// ExitCode returns the code chosen by the wrapped error, if it
+// implements ExitCoder, e.g. Check(err), or else ExitFatal.
+func (e ErrFatal) ExitCode() int {
+	var c ExitCoder
+	if e.error != nil && errors.As(e.error, &c) {
+		return c.ExitCode()
+	}
+	return ExitFatal
+}
+
 // ErrUsage is used to signal fatal errors caused by invalid commandline usage.
 type ErrUsage struct {
 	error
 }
 
+// ExitCode returns ExitUsage.
+func (ErrUsage) ExitCode() int {
+	return ExitUsage
+}
+
 // Fatal panics with an instance of ErrFatal with a formatted message.
 func Fatal(msg string, args ...interface{}) {
 	panic(ErrFatal{fmt.Errorf(msg, args...)})
@@ -28,10 +47,47 @@ func Check(err error) {
 }
 
 // Run runs the Spec with the given args. The loader is used to load
-// option values from multiple sources (flags, env, yaml, etc).
+// option values from multiple sources (flags, env, yaml, etc),
+// which are then checked by Validate.
 // Panics of type ErrFatal and ErrUsage are recovered and returned as an error,
 // all other panics are passed through.
-func Run(spec Spec, l *Loader, raw []string) (err error) {
+//
+// If the command function takes a context, the context is canceled by
+// signals, as described by DefaultSignalOpts; otherwise, signals keep
+// their default behavior, e.g. SIGINT exits immediately. The error returned by the command function, if any,
+// is returned, and the value returned by the command function, if any,
+// is written to stdout in the "text" format, see Render.
+func Run(spec Spec, l *Loader, raw []string) error {
+	return runOutput(spec, l, raw, os.Stdout, "text")
+}
+
+// runOutput runs the spec and writes its value to "w" in the given format.
+func runOutput(spec Spec, l *Loader, raw []string, w io.Writer, format string) error {
+	// Check the format before running the command,
+	// so that a typo doesn't waste the command's work.
+	if err := checkRenderFormat(format); err != nil {
+		return ErrUsage{err}
+	}
+
+	// Only a command which takes a context can stop gracefully.
+	ctx := context.Background()
+	if spec.Cmd().HasContext {
+		var stop func()
+		ctx, stop = SignalContext(ctx, DefaultSignalOpts)
+		defer stop()
+	}
+
+	val, err := RunContext(ctx, spec, l, raw)
+	if err != nil {
+		return err
+	}
+	return Render(w, val, format)
+}
+
+// RunContext is like Run, but runs the Spec with the given context,
+// doesn't handle signals, and returns the value returned by the command
+// function, if any, instead of writing it.
+func RunContext(ctx context.Context, spec Spec, l *Loader, raw []string) (val interface{}, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			switch z := r.(type) {
@@ -51,23 +107,31 @@ func Run(spec Spec, l *Loader, raw []string) (err error) {
 
 	err = validateArgs(cmd.Args, raw)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = loadArgs(cmd.Args, l, raw)
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	// load option values.
 	l.Load()
 	errs := l.Errors()
 	if errs != nil {
-		return combineErrors(errs)
+		return nil, ErrConfig{combineErrors(errs)}
 	}
 
-	spec.Run()
-	return
+	err = Validate(spec)
+	if err != nil {
+		setFlags(err, l)
+		return nil, err
+	}
+
+	setRunning(l)
+	defer setRunning(nil)
+
+	return spec.Run(ctx)
 }
 
 func combineErrors(errs []error) error {
@@ -75,7 +139,7 @@ func combineErrors(errs []error) error {
 	for _, err := range errs {
 		lines = append(lines, err.Error())
 	}
-	return fmt.Errorf(strings.Join(lines, "\n"))
+	return errors.New(strings.Join(lines, "\n"))
 }
 
 func loadArgs(args []*Arg, l *Loader, raw []string) error {
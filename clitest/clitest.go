@@ -0,0 +1,247 @@
+/*
+Package clitest runs command lines against a set of specs, such as those
+returned by the generated specs() function, in a controlled environment:
+environment variables, config files, and stdin are given by the test,
+and stdout, stderr, the exit code, and the final option values are
+returned, for example:
+
+  func TestServerRun(t *testing.T) {
+    res := clitest.Run(t, specs(), []string{"server", "run"}, clitest.RunOpts{
+      Env:   map[string]string{"APP_SERVER_ADDR": ":9090"},
+      Files: map[string]string{"config.yaml": "server:\n  name: test\n"},
+    })
+    if res.Code != 0 {
+      t.Fatal(res.Err)
+    }
+    if addr := res.Value("server.addr"); addr != ":9090" {
+      t.Errorf("unexpected addr %v", addr)
+    }
+  }
+
+Commands are built by cli.NewAutoCobra, the same way as AutoCobra builds them.
+*/
+package clitest
+
+import (
+	"flag"
+	"github.com/buchanae/cli"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// RunOpts describes the environment a command line is run in.
+type RunOpts struct {
+	// AppName is the name of the app, which is also the prefix
+	// of environment variables. Defaults to "app".
+	AppName string
+	// Env contains the environment variables seen by the env provider,
+	// instead of the environment of the process.
+	Env map[string]string
+	// Files contains the contents of config files by path, e.g. "config.yaml"
+	// or "/etc/app/config.yaml" (see cli.DefaultLayers). Config files are
+	// only read from Files, including a file given by a "--config" flag.
+	Files map[string]string
+	// Stdin is the contents of stdin.
+	Stdin string
+}
+
+// Result describes the result of running a command line.
+type Result struct {
+	// Code is the exit code, see cli.ExitCode.
+	Code int
+	// Err is the error returned by the command line, if any.
+	Err error
+	// Stdout contains everything written to stdout.
+	Stdout string
+	// Stderr contains everything written to stderr.
+	Stderr string
+	// Spec is the spec of the command which was run, or nil if the
+	// command line didn't match a command, e.g. "app --help".
+	Spec cli.Spec
+}
+
+// Opts returns the options of the command which was run,
+// which hold their final values.
+func (r *Result) Opts() []*cli.Opt {
+	if r.Spec == nil {
+		return nil
+	}
+	return r.Spec.Cmd().Opts
+}
+
+// Value returns the final value of the option with the given key,
+// e.g. "server.addr", or nil if there's no such option, or its value
+// is unreachable because of a nil pointer parent.
+func (r *Result) Value(key string) interface{} {
+	for _, opt := range r.Opts() {
+		if cli.DotKey(opt.Key) != strings.ToLower(key) {
+			continue
+		}
+		ptr := opt.Value
+		if opt.Ref != nil {
+			ptr = opt.Ref(false)
+		}
+		v := reflect.ValueOf(ptr)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return nil
+		}
+		return v.Elem().Interface()
+	}
+	return nil
+}
+
+// mu serializes runs, because the stdio of the process
+// is replaced while a command runs.
+var mu sync.Mutex
+
+// Run runs a command line, e.g. []string{"server", "run", "--addr", ":9090"},
+// against the specs. The specs are modified by the run, so a fresh set of
+// specs should be used for each call, e.g. from the generated specs().
+//
+// Option values are loaded from RunOpts instead of the environment
+// and file system of the process. The stdio of the process is replaced
+// while the command runs, so runs are serialized.
+func Run(t testing.TB, specs []cli.Spec, args []string, o RunOpts) *Result {
+	t.Helper()
+	mu.Lock()
+	defer mu.Unlock()
+
+	app := o.AppName
+	if app == "" {
+		app = "app"
+	}
+
+	dir, err := ioutil.TempDir("", "clitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stdin, stdout, stderr := os.Stdin, os.Stdout, os.Stderr
+	defer func() {
+		os.Stdin, os.Stdout, os.Stderr = stdin, stdout, stderr
+	}()
+	os.Stdin = tempFile(t, dir, "stdin", o.Stdin)
+	os.Stdout = tempFile(t, dir, "stdout", "")
+	os.Stderr = tempFile(t, dir, "stderr", "")
+	defer os.Stdin.Close()
+	defer os.Stdout.Close()
+	defer os.Stderr.Close()
+
+	b := build(app, specs, o)
+	if args == nil {
+		// cobra uses os.Args if args is nil.
+		args = []string{}
+	}
+	b.SetArgs(args)
+	err = b.Execute()
+
+	res := &Result{
+		Code:   cli.ExitCode(err),
+		Err:    err,
+		Stdout: readFile(t, os.Stdout),
+		Stderr: readFile(t, os.Stderr),
+	}
+	if target, _, err := b.Find(args); err == nil {
+		res.Spec = b.Spec(target)
+	}
+	return res
+}
+
+// build builds the commands like cli.AutoCobra, with providers which load
+// option values from "o".
+func build(app string, specs []cli.Spec, o RunOpts) *cli.Cobra {
+	lookup := func(k string) (string, bool) {
+		v, ok := o.Env[k]
+		return v, ok
+	}
+	environ := func() []string {
+		var env []string
+		for k, v := range o.Env {
+			env = append(env, k+"="+v)
+		}
+		return env
+	}
+
+	fsys := fstest.MapFS{}
+	for name, content := range o.Files {
+		name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+
+	layers := cli.DefaultLayers(app)
+	layers.FS = fsys
+	layers.LookupEnv = lookup
+
+	return cli.NewAutoCobra(app, specs, func(flags cli.Provider) []cli.Provider {
+		return []cli.Provider{
+			flags,
+			cli.EnvWith(cli.EnvOpts{
+				Prefix:    app,
+				LookupEnv: lookup,
+				Environ:   environ,
+				FS:        fsys,
+			}),
+			cli.Layered(layers),
+		}
+	})
+}
+
+// tempFile creates a file in "dir" with the given content,
+// opened for reading and writing, at the start of the file.
+func tempFile(t testing.TB, dir, name, content string) *os.File {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// readFile reads the contents of a file created by tempFile.
+func readFile(t testing.TB, f *os.File) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+var update = flag.Bool("clitest.update", false, "Update golden files.")
+
+// Golden compares "got" to the contents of the golden file at "path",
+// e.g. "testdata/help.golden", which is useful for testing help text.
+// If the -clitest.update flag is given, e.g. `go test -clitest.update`,
+// the golden file is written instead.
+func Golden(t testing.TB, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (use -clitest.update to create it)", err)
+	}
+	if string(b) != got {
+		t.Errorf("output doesn't match golden file %s\nexpected:\n%s\ngot:\n%s", path, b, got)
+	}
+}
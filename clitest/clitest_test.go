@@ -0,0 +1,147 @@
+package clitest
+
+import (
+	"context"
+	"fmt"
+	"github.com/buchanae/cli"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+type testOpt struct {
+	Name string
+	Port int
+}
+
+// testSpec is a minimal, hand-written version of a generated Spec,
+// which greets the names read from stdin.
+type testSpec struct {
+	cmd *cli.Cmd
+	opt testOpt
+}
+
+func (t *testSpec) Run(ctx context.Context) (interface{}, error) {
+	b, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Fields(string(b)) {
+		fmt.Printf("hello %s from %s:%d\n", name, t.opt.Name, t.opt.Port)
+	}
+	return nil, nil
+}
+
+func (t *testSpec) Cmd() *cli.Cmd {
+	if t.cmd != nil {
+		return t.cmd
+	}
+	t.cmd = &cli.Cmd{
+		RawName: "ServerGreet",
+		RawDoc:  "Greet names read from stdin.",
+		Opts: []*cli.Opt{
+			{Key: []string{"Name"}, RawDoc: "Server name.", Value: &t.opt.Name, DefaultValue: t.opt.Name, Type: "string"},
+			{Key: []string{"Port"}, RawDoc: "Server port.", Value: &t.opt.Port, DefaultValue: t.opt.Port, Type: "int"},
+		},
+	}
+	cli.Enrich(t.cmd)
+	return t.cmd
+}
+
+func specs() []cli.Spec {
+	return []cli.Spec{
+		&testSpec{opt: testOpt{Name: "default", Port: 8080}},
+	}
+}
+
+func TestRun(t *testing.T) {
+	os.Setenv("APP_PORT", "1")
+	defer os.Unsetenv("APP_PORT")
+
+	res := Run(t, specs(), []string{"server", "greet"}, RunOpts{
+		Env:   map[string]string{"APP_NAME": "env"},
+		Files: map[string]string{"/etc/app/config.yaml": "port: 9090\n"},
+		Stdin: "alice bob",
+	})
+	if res.Code != 0 {
+		t.Fatal(res.Err)
+	}
+
+	expect := "hello alice from env:9090\nhello bob from env:9090\n"
+	if res.Stdout != expect {
+		t.Errorf("expected stdout %q, got %q", expect, res.Stdout)
+	}
+	if res.Stderr != "" {
+		t.Errorf("unexpected stderr %q", res.Stderr)
+	}
+	if v := res.Value("name"); v != "env" {
+		t.Errorf("expected name to be env, got %v", v)
+	}
+	if v := res.Value("port"); v != 9090 {
+		t.Errorf("expected port to be 9090, got %v", v)
+	}
+	if v := res.Opts()[0].Source.String(); v != `name = "env" (env APP_NAME)` {
+		t.Errorf("unexpected source %q", v)
+	}
+
+	if os.Getenv("APP_PORT") != "1" || os.Getenv("APP_NAME") != "" {
+		t.Error("expected the process environment to be unchanged")
+	}
+}
+
+func TestRunConfigHome(t *testing.T) {
+	os.Setenv("XDG_CONFIG_HOME", "/host")
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	files := map[string]string{
+		"/host/app/config.yaml":           "port: 1\n",
+		"/cfg/app/config.yaml":            "port: 2\n",
+		"/home/u/.config/app/config.yaml": "port: 3\n",
+	}
+	tests := []struct {
+		env  map[string]string
+		port int
+	}{
+		{nil, 8080},
+		{map[string]string{"XDG_CONFIG_HOME": "/cfg"}, 2},
+		{map[string]string{"HOME": "/home/u"}, 3},
+	}
+	for _, test := range tests {
+		res := Run(t, specs(), []string{"server", "greet"}, RunOpts{
+			Env:   test.env,
+			Files: files,
+		})
+		if res.Code != 0 {
+			t.Fatal(res.Err)
+		}
+		if v := res.Value("port"); v != test.port {
+			t.Errorf("%v: expected port to be %d, got %v", test.env, test.port, v)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	res := Run(t, specs(), []string{"server", "greet", "--nope"}, RunOpts{})
+	if res.Code != cli.ExitUsage {
+		t.Errorf("expected exit code %d, got %d", cli.ExitUsage, res.Code)
+	}
+	if !strings.Contains(res.Stderr, "unknown flag: --nope") {
+		t.Errorf("expected unknown flag error, got %q", res.Stderr)
+	}
+
+	res = Run(t, specs(), []string{"server", "greet"}, RunOpts{
+		Files: map[string]string{"config.yaml": "port: nope\n"},
+	})
+	if res.Code != cli.ExitConfig {
+		t.Errorf("expected exit code %d, got %d: %v", cli.ExitConfig, res.Code, res.Err)
+	}
+}
+
+func TestGoldenHelp(t *testing.T) {
+	res := Run(t, specs(), []string{"server", "greet", "--help"}, RunOpts{})
+	if res.Code != 0 {
+		t.Fatal(res.Err)
+	}
+	Golden(t, "testdata/help.golden", res.Stdout)
+}
@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"strings"
 )
 
 // Cobra helps build a set of cobra commands.
@@ -9,6 +12,17 @@ import (
 type Cobra struct {
 	cobra.Command
 	KeyFunc
+	runners map[*cobra.Command]*cobraRunner
+	// specs tracks the specs added by Add, in order, for AddDocs.
+	specs []Spec
+	// commands maps the commands created by Add to their specs.
+	commands map[*cobra.Command]Spec
+}
+
+// cobraRunner tracks the spec and loader of a command added by SetRunner.
+type cobraRunner struct {
+	spec   Spec
+	loader *Loader
 }
 
 // Add adds a command to the tree.
@@ -37,13 +51,194 @@ func (cb *Cobra) Add(spec Spec) *cobra.Command {
 	}
 
 	parent.AddCommand(x)
+	if cb.commands == nil {
+		cb.commands = map[*cobra.Command]Spec{}
+	}
+	cb.commands[x] = spec
+	cb.specs = append(cb.specs, spec)
 	return x
 }
 
+// hasCommand returns true if the root command has a subcommand
+// with the given name or alias.
+func (cb *Cobra) hasCommand(name string) bool {
+	for _, c := range cb.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Spec returns the spec of a command created by Add, or nil.
+func (cb *Cobra) Spec(cmd *cobra.Command) Spec {
+	return cb.commands[cmd]
+}
+
+// Execute runs the command tree, like cobra.Command.Execute, but errors
+// caused by invalid usage, such as unknown flags and commands, are returned
+// as ErrUsage, and the usage of the command is printed after ErrUsage errors.
+// Use Exit to exit with the exit code for the error.
+func (cb *Cobra) Execute() error {
+	cb.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return ErrUsage{err}
+	})
+
+	cmd, err := cb.ExecuteC()
+	switch err.(type) {
+	case nil:
+	case ErrUsage:
+		cmd.Println(cmd.UsageString())
+	default:
+		// cobra doesn't have a distinct error type for unknown commands,
+		// and already suggests using --help.
+		if strings.HasPrefix(err.Error(), "unknown command ") {
+			err = ErrUsage{err}
+		}
+	}
+	return err
+}
+
 // SetRunner sets `cobra.Command.RunE` to use the loader and runner
 // from this package.
+//
+// If the command function returns a value (see Cmd.Returns), an "--output"
+// flag is added, which selects the format the value is written in,
+// see Render.
 func (cb *Cobra) SetRunner(cmd *cobra.Command, spec Spec, l *Loader) {
-	cmd.RunE = func(_ *cobra.Command, args []string) error {
-		return Run(spec, l, args)
+	if cb.runners == nil {
+		cb.runners = map[*cobra.Command]*cobraRunner{}
+	}
+	cb.runners[cmd] = &cobraRunner{spec, l}
+
+	format := "text"
+	fs := cmd.Flags()
+	if spec.Cmd().Returns != "" && fs.Lookup("output") == nil {
+		short := "o"
+		if fs.ShorthandLookup(short) != nil {
+			short = ""
+		}
+		fs.StringVarP(&format, "output", short, format, "Output format: text, json, yaml, or table.")
+	}
+
+	cmd.RunE = func(x *cobra.Command, args []string) error {
+		return runOutput(spec, l, args, x.OutOrStdout(), format)
+	}
+}
+
+// AddDumpConfig adds a "dump-config" command, which loads the options
+// of another command and writes the effective configuration instead of
+// running the command. The other command's path and flags follow the
+// dump-config flags, for example:
+//
+//   app dump-config --format json server run --addr :9090
+//
+// Only commands configured with SetRunner can be dumped.
+//
+// If a command named "dump-config" was already added, e.g. by a spec
+// with the doc annotation "Name: dump-config", that command is kept,
+// and AddDumpConfig returns nil.
+func (cb *Cobra) AddDumpConfig() *cobra.Command {
+	if cb.hasCommand("dump-config") {
+		return nil
+	}
+	d := DumpOpts{Prefix: cb.Name()}
+
+	x := &cobra.Command{
+		Use:   "dump-config [flags] command [command flags]",
+		Short: "Write the effective configuration of a command.",
+		Long: `Write the effective configuration of a command.
+
+Options are loaded from all sources (flags, env. vars, config files, etc)
+and written in the given format instead of running the command.
+Formats: yaml, json, toml, env, flags.`,
+		DisableFlagParsing: true,
+	}
+
+	fs := x.Flags()
+	fs.SetInterspersed(false)
+	fs.StringVarP(&d.Format, "format", "f", "yaml", "Output format.")
+	fs.BoolVar(&d.ExcludeDefaults, "exclude-defaults", false, "Omit options equal to their default value.")
+	fs.BoolVar(&d.OmitDocs, "omit-docs", false, "Omit option docs.")
+
+	x.RunE = func(x *cobra.Command, args []string) error {
+		err := fs.Parse(args)
+		if help, _ := fs.GetBool("help"); help || err == pflag.ErrHelp {
+			return x.Help()
+		}
+		if err != nil {
+			return err
+		}
+
+		target, rest, err := cb.Command.Find(fs.Args())
+		if err != nil {
+			return err
+		}
+		r, ok := cb.runners[target]
+		if !ok {
+			return fmt.Errorf("can't dump config for command %q", target.CommandPath())
+		}
+
+		err = target.ParseFlags(rest)
+		if err != nil {
+			return err
+		}
+
+		l := r.loader
+		l.Load()
+		if errs := l.Errors(); errs != nil {
+			return ErrConfig{combineErrors(errs)}
+		}
+
+		return l.Dump(x.OutOrStdout(), d)
 	}
+
+	cb.AddCommand(x)
+	return x
+}
+
+// AddDocs adds a hidden "docs" command, which writes reference documentation
+// for all commands added by Add, as Markdown, HTML, or man pages, for example:
+//
+//   app docs --format html > reference.html
+//   app docs --format man --dir ./man
+//
+// Environment variables are documented with the app name as the prefix.
+//
+// If a command named "docs" was already added, e.g. for a function named
+// Docs, that command is kept, and AddDocs returns nil.
+func (cb *Cobra) AddDocs() *cobra.Command {
+	if cb.hasCommand("docs") {
+		return nil
+	}
+	d := DocOpts{
+		AppName:   cb.Name(),
+		EnvPrefix: cb.Name(),
+		KeyFunc:   cb.KeyFunc,
+	}
+	var dir string
+
+	x := &cobra.Command{
+		Use:    "docs",
+		Short:  "Write reference documentation for all commands.",
+		Args:   cobra.NoArgs,
+		Hidden: true,
+	}
+
+	fs := x.Flags()
+	fs.StringVarP(&d.Format, "format", "f", "markdown", "Output format: markdown, html, or man.")
+	fs.StringVar(&dir, "dir", "", "Write one man page per command to this directory.")
+
+	x.RunE = func(x *cobra.Command, args []string) error {
+		if dir != "" {
+			if d.Format != "man" {
+				return fmt.Errorf("--dir is only supported by the man format")
+			}
+			return ManPages(dir, cb.specs, d)
+		}
+		return Docs(x.OutOrStdout(), cb.specs, d)
+	}
+
+	cb.AddCommand(x)
+	return x
 }
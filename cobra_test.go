@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+type testOpt struct {
+	Name string
+	Port int
+}
+
+// testSpec is a minimal, hand-written version of a generated Spec.
+type testSpec struct {
+	cmd *Cmd
+	opt testOpt
+	ran bool
+	ctx context.Context
+}
+
+func (t *testSpec) Run(ctx context.Context) (interface{}, error) {
+	t.ran = true
+	t.ctx = ctx
+	return nil, nil
+}
+
+func (t *testSpec) Cmd() *Cmd {
+	if t.cmd != nil {
+		return t.cmd
+	}
+	t.cmd = &Cmd{
+		RawName: "ServerRun",
+		RawDoc:  "Run a server.",
+		Opts: []*Opt{
+			{Key: []string{"Name"}, RawDoc: "Server name.", Value: &t.opt.Name, DefaultValue: t.opt.Name, Type: "string"},
+			{Key: []string{"Port"}, Value: &t.opt.Port, DefaultValue: t.opt.Port, Type: "int"},
+		},
+	}
+	Enrich(t.cmd)
+	return t.cmd
+}
+
+func TestDumpConfig(t *testing.T) {
+	spec := &testSpec{opt: testOpt{Name: "default", Port: 8080}}
+
+	b := Cobra{}
+	b.Use = "app"
+	cmd := b.Add(spec)
+	opts := spec.Cmd().Opts
+	l := NewLoader(opts, PFlags(cmd.Flags(), opts, DotKey))
+	b.SetRunner(cmd, spec, l)
+	b.AddDumpConfig()
+
+	out := &bytes.Buffer{}
+	b.SetOutput(out)
+	b.SetArgs([]string{"dump-config", "--format", "flags", "--exclude-defaults", "server", "run", "--port", "9090"})
+
+	err := b.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.ran {
+		t.Error("expected command not to run")
+	}
+
+	expect := "--port=9090\n"
+	if out.String() != expect {
+		t.Errorf("expected %q got %q", expect, out.String())
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	spec := &testSpec{}
+	opts := spec.Cmd().Opts
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := RunContext(ctx, spec, NewLoader(opts), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.ctx != ctx {
+		t.Error("expected the command to run with the given context")
+	}
+}
+
+// testListSpec is a spec whose command function returns a value.
+type testListSpec struct {
+	cmd *Cmd
+	err error
+}
+
+func (t *testListSpec) Run(ctx context.Context) (interface{}, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return []testTask{{ID: 1, Description: "write docs"}}, nil
+}
+
+func (t *testListSpec) Cmd() *Cmd {
+	if t.cmd == nil {
+		t.cmd = &Cmd{RawName: "List", Returns: "[]testTask"}
+		Enrich(t.cmd)
+	}
+	return t.cmd
+}
+
+func TestCobraOutput(t *testing.T) {
+	spec := &testListSpec{}
+	b := Cobra{}
+	b.Use = "app"
+	cmd := b.Add(spec)
+	b.SetRunner(cmd, spec, NewLoader(nil))
+
+	out := &bytes.Buffer{}
+	b.SetOutput(out)
+	b.SetArgs([]string{"list", "-o", "json"})
+	if err := b.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	expect := "[\n  {\n    \"ID\": 1,\n    \"Description\": \"write docs\"\n  }\n]\n"
+	if out.String() != expect {
+		t.Errorf("expected %q, got %q", expect, out.String())
+	}
+
+	spec.err = errors.New("db is locked")
+	b.SetArgs([]string{"list"})
+	if err := b.Execute(); err != spec.err {
+		t.Errorf("expected the command's error, got %v", err)
+	}
+}
+
+// testBuiltinCollision checks that a spec with the same name as a built-in
+// command, e.g. "docs", replaces the built-in command.
+func testBuiltinCollision(t *testing.T, name string) {
+	t.Helper()
+	cmd := &Cmd{RawName: "Other", RawDoc: "A user command.\nName: " + name}
+	Enrich(cmd)
+	spec := &testSpec{cmd: cmd}
+
+	b := NewAutoCobra("app", []Spec{spec}, func(flags Provider) []Provider {
+		return []Provider{flags}
+	})
+	var found int
+	for _, c := range b.Commands() {
+		if c.Name() == name {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Fatalf("expected one %q command, got %d", name, found)
+	}
+
+	b.SetOutput(&bytes.Buffer{})
+	b.SetArgs([]string{name})
+	if err := b.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !spec.ran {
+		t.Errorf("expected the %q spec to run", name)
+	}
+}
+
+func TestDumpConfigCollision(t *testing.T) {
+	testBuiltinCollision(t, "dump-config")
+}
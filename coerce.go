@@ -1,12 +1,40 @@
 package cli
 
 import (
+	"encoding"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/spf13/cast"
+	"net/url"
+	"reflect"
+	"strings"
 	"time"
 )
 
+// TimeLayouts lists the layouts used to parse time.Time values
+// from strings, in order. See time.Parse.
+var TimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
 // Coerce attempts to coerce "val" to the type of "dest".
+//
+// Common types are handled directly. Any other basic kind (e.g. uint16, int8),
+// slices, arrays, and maps of those, and named types with one of those
+// underlying types (e.g. `type Level string`) are handled using reflection.
+// Strings are split into lists on commas or whitespace, and into maps
+// on commas and "=", e.g. "a=1,b=2". Structs, such as the elements of
+// a list of structs, are coerced from maps of field names to values.
+//
+// If the destination implements encoding.TextUnmarshaler, flag.Value
+// (which includes pflag.Value), or json.Unmarshaler, coercion is delegated
+// to it, e.g. for net.IP or big.Int. Pointer destinations, such as
+// **url.URL, are allocated as needed; url.URL values are parsed with url.Parse.
 func Coerce(dest interface{}, val interface{}) error {
 
 	switch z := dest.(type) {
@@ -59,34 +87,358 @@ func Coerce(dest interface{}, val interface{}) error {
 		}
 		*z = casted
 		return nil
-	case *[]string:
-		casted, err := cast.ToStringSliceE(val)
+	case *time.Duration:
+		casted, err := cast.ToDurationE(val)
 		if err != nil {
 			return err
 		}
 		*z = casted
 		return nil
-	case *map[string]string:
-		casted, err := cast.ToStringMapStringE(val)
+	case *time.Time:
+		casted, err := coerceTime(val)
 		if err != nil {
 			return err
 		}
 		*z = casted
 		return nil
-	case *[]int:
-		casted, err := cast.ToIntSliceE(val)
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("cannot coerce %T to %T, unknown type %T", val, dest, dest)
+	}
+	return coerceValue(v.Elem(), val)
+}
+
+// coerceTime coerces a string using TimeLayouts,
+// or a time.Time or Unix timestamp using cast.
+func coerceTime(val interface{}) (time.Time, error) {
+	s, ok := val.(string)
+	if !ok {
+		return cast.ToTimeE(val)
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range TimeLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a time, expected a layout such as %q", s, TimeLayouts[0])
+}
+
+// coerceValue coerces "val" to the type of "dst", using reflection.
+func coerceValue(dst reflect.Value, val interface{}) error {
+	t := dst.Type()
+
+	if val == nil {
+		dst.Set(reflect.Zero(t))
+		return nil
+	}
+	if v := reflect.ValueOf(val); v.Type().AssignableTo(t) {
+		dst.Set(v)
+		return nil
+	}
+
+	// Common types are handled by Coerce. This also handles
+	// slices and maps of durations, times, etc.
+	switch t {
+	case durationType, timeType:
+		ptr := reflect.New(t)
+		if err := Coerce(ptr.Interface(), val); err != nil {
+			return err
+		}
+		dst.Set(ptr.Elem())
+		return nil
+	}
+
+	if ok, err := unmarshalValue(dst, val); ok {
+		return err
+	}
+
+	// cast doesn't understand named types, such as `type Level string`,
+	// so convert those to their basic type.
+	val = basicValue(val)
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := cast.ToBoolE(val)
 		if err != nil {
 			return err
 		}
-		*z = casted
+		dst.SetBool(b)
 		return nil
-	case *time.Duration:
-		casted, err := cast.ToDurationE(val)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := cast.ToInt64E(val)
 		if err != nil {
 			return err
 		}
-		*z = casted
+		if dst.OverflowInt(i) {
+			return fmt.Errorf("value %v overflows %s", val, t)
+		}
+		dst.SetInt(i)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := cast.ToUint64E(val)
+		if err != nil {
+			return err
+		}
+		if dst.OverflowUint(u) {
+			return fmt.Errorf("value %v overflows %s", val, t)
+		}
+		dst.SetUint(u)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := cast.ToFloat64E(val)
+		if err != nil {
+			return err
+		}
+		if dst.OverflowFloat(f) {
+			return fmt.Errorf("value %v overflows %s", val, t)
+		}
+		dst.SetFloat(f)
+		return nil
+
+	case reflect.String:
+		s, err := cast.ToStringE(val)
+		if err != nil {
+			return err
+		}
+		dst.SetString(s)
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		items, err := listItems(val)
+		if err != nil {
+			return fmt.Errorf("cannot coerce %T to %s: %v", val, t, err)
+		}
+		if t.Kind() == reflect.Array && len(items) > t.Len() {
+			return fmt.Errorf("cannot coerce %d values to %s", len(items), t)
+		}
+
+		out := dst
+		if t.Kind() == reflect.Slice {
+			out = reflect.MakeSlice(t, len(items), len(items))
+		} else {
+			out = reflect.New(t).Elem()
+		}
+		for i, item := range items {
+			if err := coerceValue(out.Index(i), item); err != nil {
+				return fmt.Errorf("index %d: %v", i, err)
+			}
+		}
+		dst.Set(out)
+		return nil
+
+	case reflect.Map:
+		entries, err := mapEntries(val)
+		if err != nil {
+			return fmt.Errorf("cannot coerce %T to %s: %v", val, t, err)
+		}
+
+		out := reflect.MakeMapWithSize(t, len(entries))
+		for _, e := range entries {
+			k := reflect.New(t.Key()).Elem()
+			if err := coerceValue(k, e[0]); err != nil {
+				return fmt.Errorf("key %v: %v", e[0], err)
+			}
+			v := reflect.New(t.Elem()).Elem()
+			if err := coerceValue(v, e[1]); err != nil {
+				return fmt.Errorf("key %v: %v", e[0], err)
+			}
+			out.SetMapIndex(k, v)
+		}
+		dst.Set(out)
+		return nil
+
+	case reflect.Struct:
+		entries, err := mapEntries(val)
+		if err != nil {
+			return fmt.Errorf("cannot coerce %T to %s: %v", val, t, err)
+		}
+
+		out := reflect.New(t).Elem()
+		for _, e := range entries {
+			name := fmt.Sprint(e[0])
+			f, ok := t.FieldByNameFunc(func(n string) bool {
+				return fieldNameEq(n, name)
+			})
+			if !ok || f.PkgPath != "" {
+				return fmt.Errorf("cannot coerce to %s: unknown field %q", t, name)
+			}
+			if err := coerceValue(out.FieldByIndex(f.Index), e[1]); err != nil {
+				return fmt.Errorf("field %s: %v", name, err)
+			}
+		}
+		dst.Set(out)
+		return nil
+
+	case reflect.Ptr:
+		ptr := reflect.New(t.Elem())
+		if err := coerceValue(ptr.Elem(), val); err != nil {
+			return err
+		}
+		dst.Set(ptr)
 		return nil
 	}
-	return fmt.Errorf("cannot coerce %T to %T, unknown type %T", val, dest, dest)
+
+	return fmt.Errorf("cannot coerce %T to %s, unknown type %s", val, t, t)
+}
+
+var urlType = reflect.TypeOf(url.URL{})
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+)
+
+// isUnmarshaler returns true if the pointer type "t" is coerced by
+// unmarshalValue, rather than by its kind.
+func isUnmarshaler(t reflect.Type) bool {
+	return t.Elem() == urlType ||
+		t.Implements(textUnmarshalerType) ||
+		t.Implements(jsonUnmarshalerType) ||
+		t.Implements(flagValueType)
+}
+
+// unmarshalValue delegates coercion to the interfaces implemented
+// by a pointer to "dst", if any. Returns false if "dst" doesn't
+// implement any of them.
+func unmarshalValue(dst reflect.Value, val interface{}) (bool, error) {
+	if !dst.CanAddr() {
+		return false, nil
+	}
+
+	if dst.Type() == urlType {
+		s, err := cast.ToStringE(val)
+		if err != nil {
+			return true, err
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return true, err
+		}
+		dst.Set(reflect.ValueOf(*u))
+		return true, nil
+	}
+
+	switch z := dst.Addr().Interface().(type) {
+	case encoding.TextUnmarshaler:
+		s, err := cast.ToStringE(basicValue(val))
+		if err != nil {
+			return true, err
+		}
+		return true, z.UnmarshalText([]byte(s))
+
+	case flag.Value:
+		s, err := cast.ToStringE(basicValue(val))
+		if err != nil {
+			return true, err
+		}
+		return true, z.Set(s)
+
+	case json.Unmarshaler:
+		// Strings which are valid JSON documents, e.g. from an environment
+		// variable, are passed as-is. Other values are marshaled first.
+		if s, ok := val.(string); ok && json.Valid([]byte(s)) {
+			return true, z.UnmarshalJSON([]byte(s))
+		}
+		b, err := json.Marshal(val)
+		if err != nil {
+			return true, err
+		}
+		return true, z.UnmarshalJSON(b)
+	}
+	return false, nil
+}
+
+// fieldNameEq returns true if a config key matches a struct field name.
+// Matching is case insensitive and ignores dashes and underscores,
+// so "max_weight" matches "MaxWeight".
+func fieldNameEq(field, key string) bool {
+	strip := strings.NewReplacer("-", "", "_", "")
+	return strings.EqualFold(field, strip.Replace(key))
+}
+
+// basicValue converts a value of a named basic type,
+// e.g. `type Level string`, to its underlying type.
+func basicValue(val interface{}) interface{} {
+	v := reflect.ValueOf(val)
+	t := v.Type()
+	if t.PkgPath() == "" || t == durationType {
+		return val
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	}
+	return val
+}
+
+// listItems returns the items of a slice or array value.
+// A string is split on commas and whitespace.
+func listItems(val interface{}) ([]interface{}, error) {
+	if s, ok := val.(string); ok {
+		var items []interface{}
+		for _, f := range splitList(s) {
+			items = append(items, f)
+		}
+		return items, nil
+	}
+
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var items []interface{}
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, v.Index(i).Interface())
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("expected a list")
+}
+
+// mapEntries returns the key/value pairs of a map value.
+// A string is parsed as comma-separated key=value pairs.
+func mapEntries(val interface{}) ([][2]interface{}, error) {
+	var entries [][2]interface{}
+
+	if s, ok := val.(string); ok {
+		for _, f := range splitList(s) {
+			kv := strings.SplitN(f, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("expected key=value, got %q", f)
+			}
+			entries = append(entries, [2]interface{}{kv[0], kv[1]})
+		}
+		return entries, nil
+	}
+
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Map {
+		return nil, fmt.Errorf("expected a map")
+	}
+	for _, k := range v.MapKeys() {
+		entries = append(entries, [2]interface{}{k.Interface(), v.MapIndex(k).Interface()})
+	}
+	return entries, nil
+}
+
+// splitList splits a string on commas and whitespace, e.g. "a, b c".
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
 }
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testLevel string
+
+// testFlagValue implements flag.Value.
+type testFlagValue struct{ parts []string }
+
+func (t *testFlagValue) String() string { return strings.Join(t.parts, "+") }
+
+func (t *testFlagValue) Set(s string) error {
+	t.parts = strings.Split(s, "+")
+	return nil
+}
+
+// testJSONValue implements json.Unmarshaler.
+type testJSONValue struct{ raw string }
+
+func (t *testJSONValue) UnmarshalJSON(b []byte) error {
+	t.raw = string(b)
+	return nil
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		val    interface{}
+		expect interface{}
+	}{
+		{"80", uint16(80)},
+		{float64(80), uint16(80)},
+		{"-3", int8(-3)},
+		{true, true},
+		{"1.5, 2", []float64{1.5, 2}},
+		{[]interface{}{"1s", "1m"}, []time.Duration{time.Second, time.Minute}},
+		{"a=1,b=2", map[string]int{"a": 1, "b": 2}},
+		{map[string]interface{}{"a": float64(1)}, map[string]int{"a": 1}},
+		{"true false", []bool{true, false}},
+		{"debug", testLevel("debug")},
+		{[]interface{}{"info"}, []testLevel{"info"}},
+		{"1 2", [2]uint{1, 2}},
+		{"2019-01-02", time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"2019-01-02T03:04:05Z", time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2019-01-02 03:04:05", time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"10.0.0.1", net.ParseIP("10.0.0.1")},
+		{"10.0.0.1, 10.0.0.2", []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}},
+		{"a+b", testFlagValue{[]string{"a", "b"}}},
+		{`{"a":1}`, testJSONValue{`{"a":1}`}},
+		{map[string]interface{}{"a": 1}, testJSONValue{`{"a":1}`}},
+		{"x", testJSONValue{`"x"`}},
+
+		// Types handled directly by Coerce.
+		{"3", 3},
+		{"-3", int64(-3)},
+		{"3", int32(3)},
+		{"1.5", float32(1.5)},
+		{"1.5", 1.5},
+		{"true", true},
+		{8080, "8080"},
+		{"1m", time.Minute},
+		{"a,b", []string{"a", "b"}},
+		{"a b", []string{"a", "b"}},
+		{[]interface{}{"a", "b"}, []string{"a", "b"}},
+		{"1,2", []int{1, 2}},
+		{[]interface{}{float64(1), "2"}, []int{1, 2}},
+		{"a=1,b=2", map[string]string{"a": "1", "b": "2"}},
+		{map[string]interface{}{"a": 1}, map[string]string{"a": "1"}},
+	}
+
+	for _, test := range tests {
+		dest := reflect.New(reflect.TypeOf(test.expect))
+		err := Coerce(dest.Interface(), test.val)
+		if err != nil {
+			t.Errorf("coercing %#v to %T: %v", test.val, test.expect, err)
+			continue
+		}
+		got := dest.Elem().Interface()
+		if !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("coercing %#v: expected %#v, got %#v", test.val, test.expect, got)
+		}
+	}
+}
+
+func TestCoercePointers(t *testing.T) {
+	var u *url.URL
+	err := Coerce(&u, "https://example.com/path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u == nil || u.Host != "example.com" {
+		t.Errorf("unexpected url %#v", u)
+	}
+
+	var n big.Int
+	err = Coerce(&n, "123456789012345678901234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.String() != "123456789012345678901234567890" {
+		t.Errorf("unexpected big.Int %s", &n)
+	}
+}
+
+func TestCoerceErrors(t *testing.T) {
+	tests := []struct {
+		dest interface{}
+		val  interface{}
+	}{
+		{new(uint8), "300"},
+		{new(uint), "-1"},
+		{new(int8), 200},
+		{new([]int), "1,x"},
+		{new(map[string]int), "a"},
+		{new([1]int), "1 2"},
+		{new(time.Time), "yesterday"},
+		{new(chan int), "x"},
+		{new(net.IP), 5},
+		{new(big.Int), "x"},
+	}
+
+	for _, test := range tests {
+		err := Coerce(test.dest, test.val)
+		if err == nil {
+			t.Errorf("expected error coercing %#v to %T", test.val, test.dest)
+		}
+	}
+}
+
+type testBackend struct {
+	Addr      string
+	MaxWeight int
+	TLS       struct {
+		Cert string
+	}
+}
+
+// testBackendOpt returns an option for a list of testBackend,
+// as generated by the code generator.
+func testBackendOpt(val *[]testBackend) *Opt {
+	opt := &Opt{
+		Key:   []string{"Backends"},
+		Value: val,
+		Fields: []*Opt{
+			{Key: []string{"Addr"}, RawDoc: "Backend address.", Type: "string", Tag: `validate:"required"`},
+			{Key: []string{"MaxWeight"}, Type: "int", Tag: `validate:"max=10"`},
+			{Key: []string{"TLS", "Cert"}, Type: "string"},
+		},
+	}
+	Enrich(&Cmd{Opts: []*Opt{opt}})
+	return opt
+}
+
+func TestCoerceStructs(t *testing.T) {
+	var got []testBackend
+	err := Coerce(&got, []interface{}{
+		map[string]interface{}{"addr": "a:80", "max_weight": float64(2), "tls": map[string]interface{}{"cert": "c.pem"}},
+		map[string]interface{}{"Addr": "b:80"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []testBackend{{Addr: "a:80", MaxWeight: 2}, {Addr: "b:80"}}
+	expect[0].TLS.Cert = "c.pem"
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %+v, got %+v", expect, got)
+	}
+
+	err = Coerce(&got, []interface{}{map[string]interface{}{"adr": "a:80"}})
+	if err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
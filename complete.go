@@ -0,0 +1,373 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Completer is implemented by specs which complete positional arguments
+// dynamically, e.g. by listing the names of resources. The code generator
+// implements Completer for a command function, e.g. ServerRun, when
+// a function named CompleteServerRun is declared next to it:
+//
+//   func CompleteServerRun(args []string, toComplete string) []string
+//
+// "args" contains the arguments before the one being completed, and
+// "toComplete" is the partial argument. Candidates which don't start
+// with toComplete are ignored.
+type Completer interface {
+	Complete(args []string, toComplete string) []string
+}
+
+// Completion directives are written on the first line of the output
+// of the "__complete" command, followed by the candidates, one per line.
+// The directive tells the completion script what to complete.
+const (
+	// completeValues completes the candidates.
+	completeValues = "values"
+	// completeFiles completes file paths.
+	completeFiles = "file"
+	// completeDirs completes directory paths.
+	completeDirs = "dir"
+)
+
+// AddCompletion adds a "completion" command, which writes a completion
+// script for bash, zsh, fish, or powershell, for example:
+//
+//   source <(app completion bash)
+//
+// The scripts call a hidden "__complete" command, which completes
+// subcommands and aliases, flags, values of options with a "oneof" rule,
+// and booleans. Options and arguments with a "file" or "dir" rule
+// complete paths. Other arguments are completed by the spec, if it
+// implements Completer, or else complete file paths.
+//
+// If a command named "completion" was already added, e.g. for a function
+// named Completion, that command is kept, and AddCompletion returns nil.
+func (cb *Cobra) AddCompletion() *cobra.Command {
+	if cb.hasCommand("completion") {
+		return nil
+	}
+	x := &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Write a shell completion script.",
+		Long: fmt.Sprintf(`Write a shell completion script.
+
+To load completions in the current shell:
+
+  bash:       source <(%[1]s completion bash)
+  zsh:        source <(%[1]s completion zsh)
+  fish:       %[1]s completion fish | source
+  powershell: %[1]s completion powershell | Out-String | Invoke-Expression`, cb.Name()),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Args:      cobra.ExactArgs(1),
+	}
+
+	x.RunE = func(x *cobra.Command, args []string) error {
+		script, ok := completionScripts[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown shell %q, expected one of: bash, zsh, fish, powershell", args[0])
+		}
+		fn := nonIdent.ReplaceAllString(cb.Name(), "_")
+		_, err := fmt.Fprintf(x.OutOrStdout(), script, cb.Name(), fn)
+		return err
+	}
+
+	c := &cobra.Command{
+		Use:                "__complete [words]",
+		Short:              "Complete a command line, for completion scripts.",
+		Hidden:             true,
+		DisableFlagParsing: true,
+		RunE: func(x *cobra.Command, args []string) error {
+			directive, candidates := cb.complete(args)
+			w := x.OutOrStdout()
+			fmt.Fprintln(w, directive)
+			for _, c := range candidates {
+				fmt.Fprintln(w, c)
+			}
+			return nil
+		},
+	}
+
+	cb.AddCommand(x)
+	if !cb.hasCommand("__complete") {
+		cb.AddCommand(c)
+	}
+	return x
+}
+
+var nonIdent = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// complete returns the completion directive and candidates for the given
+// words, which follow the app name. The last word is the one being completed,
+// and may be empty.
+func (cb *Cobra) complete(words []string) (string, []string) {
+	toComplete := ""
+	if len(words) > 0 {
+		toComplete = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	// Older versions of PowerShell drop empty arguments,
+	// so the script passes a quoted empty string instead.
+	if toComplete == `""` {
+		toComplete = ""
+	}
+
+	target, rest, err := cb.Command.Find(words)
+	if err != nil {
+		return completeValues, nil
+	}
+	fs := target.Flags()
+
+	// Find the positional args, and whether the word being completed
+	// is the value of a flag, e.g. "--format <TAB>".
+	var args []string
+	var pending *pflag.Flag
+	for _, w := range rest {
+		switch {
+		case pending != nil && w == "=":
+			// bash splits "--format=" into "--format" and "=".
+		case pending != nil:
+			pending = nil
+		case strings.HasPrefix(w, "-") && w != "-":
+			f := lookupFlag(fs, w)
+			if f != nil && f.NoOptDefVal == "" && !strings.Contains(w, "=") {
+				pending = f
+			}
+		default:
+			args = append(args, w)
+		}
+	}
+
+	switch {
+	case pending != nil:
+		return completeOpt(flagOpt(pending), toComplete)
+
+	case strings.HasPrefix(toComplete, "-"):
+		if i := strings.Index(toComplete, "="); i >= 0 {
+			f := lookupFlag(fs, toComplete[:i])
+			if f == nil {
+				return completeValues, nil
+			}
+			prefix := toComplete[:i+1]
+			directive, candidates := completeOpt(flagOpt(f), toComplete[i+1:])
+			for j, c := range candidates {
+				candidates[j] = prefix + c
+			}
+			return directive, candidates
+		}
+
+		var names []string
+		fs.VisitAll(func(f *pflag.Flag) {
+			if f.Hidden || f.Deprecated != "" {
+				return
+			}
+			names = append(names, "--"+f.Name)
+			if f.Shorthand != "" {
+				names = append(names, "-"+f.Shorthand)
+			}
+		})
+		return completeValues, filterPrefix(names, toComplete)
+
+	case target.HasAvailableSubCommands() && len(args) == 0:
+		var names []string
+		for _, sub := range target.Commands() {
+			if !sub.IsAvailableCommand() {
+				continue
+			}
+			names = append(names, sub.Name())
+			names = append(names, sub.Aliases...)
+		}
+		return completeValues, filterPrefix(names, toComplete)
+	}
+
+	spec, ok := cb.commands[target]
+	if !ok {
+		return completeValues, nil
+	}
+	return completeArg(spec, args, toComplete)
+}
+
+// completeArg completes a positional argument of the spec.
+func completeArg(spec Spec, args []string, toComplete string) (string, []string) {
+	cmdArgs := spec.Cmd().Args
+	var arg *Arg
+	switch i := len(args); {
+	case i < len(cmdArgs):
+		arg = cmdArgs[i]
+	case len(cmdArgs) > 0 && cmdArgs[len(cmdArgs)-1].Variadic:
+		arg = cmdArgs[len(cmdArgs)-1]
+	default:
+		return completeValues, nil
+	}
+
+	if directive, candidates, ok := completeRules(arg.Rules); ok {
+		return directive, filterPrefix(candidates, toComplete)
+	}
+	if c, ok := spec.(Completer); ok {
+		return completeValues, filterPrefix(c.Complete(args, toComplete), toComplete)
+	}
+	return completeFiles, nil
+}
+
+// completeOpt completes the value of an option.
+func completeOpt(opt *Opt, toComplete string) (string, []string) {
+	if opt == nil {
+		return completeValues, nil
+	}
+	if directive, candidates, ok := completeRules(opt.Rules); ok {
+		return directive, filterPrefix(candidates, toComplete)
+	}
+	if newPflagValue(opt, "").kind == reflect.Bool {
+		return completeValues, filterPrefix([]string{"true", "false"}, toComplete)
+	}
+	return completeValues, nil
+}
+
+// completeRules returns completions based on validation rules,
+// e.g. the values of a "oneof" rule. Returns false if no rule applies.
+func completeRules(rules []Rule) (string, []string, bool) {
+	for _, r := range rules {
+		switch r.Name {
+		case "oneof":
+			return completeValues, strings.Fields(r.Arg), true
+		case "file":
+			return completeFiles, nil, true
+		case "dir":
+			return completeDirs, nil, true
+		}
+	}
+	return "", nil, false
+}
+
+// lookupFlag returns the flag for a word such as "--name", "--name=value",
+// or "-n", or nil if there's no such flag.
+func lookupFlag(fs *pflag.FlagSet, word string) *pflag.Flag {
+	word = strings.SplitN(word, "=", 2)[0]
+	if strings.HasPrefix(word, "--") {
+		return fs.Lookup(strings.TrimPrefix(word, "--"))
+	}
+	if len(word) == 2 {
+		return fs.ShorthandLookup(word[1:])
+	}
+	return nil
+}
+
+// flagOpt returns the option of a flag created by PFlags, or nil.
+func flagOpt(f *pflag.Flag) *Opt {
+	switch v := f.Value.(type) {
+	case *pflagValue:
+		return v.opt
+	case *pflagElem:
+		return v.field
+	}
+	return nil
+}
+
+func filterPrefix(list []string, prefix string) []string {
+	var out []string
+	for _, s := range list {
+		if strings.HasPrefix(s, prefix) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// completionScripts are formatted with the app name and the app name
+// as a valid identifier, for function names.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for %[1]s
+_%[2]s_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local directive="" line
+    COMPREPLY=()
+    while IFS='' read -r line; do
+        if [ -z "$directive" ]; then
+            directive="$line"
+        else
+            COMPREPLY+=("$line")
+        fi
+    done < <("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+
+    case "$directive" in
+    file)
+        compopt -o filenames 2>/dev/null
+        COMPREPLY=($(compgen -f -- "$cur"))
+        ;;
+    dir)
+        compopt -o filenames 2>/dev/null
+        COMPREPLY=($(compgen -d -- "$cur"))
+        ;;
+    esac
+}
+complete -F _%[2]s_complete %[1]s
+`,
+
+	"zsh": `#compdef %[1]s
+# zsh completion for %[1]s
+_%[2]s() {
+    local -a lines candidates
+    local directive
+    lines=("${(@f)$(${words[1]} __complete "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+    directive=${lines[1]}
+    candidates=("${(@)lines[2,-1]}")
+
+    case $directive in
+    file) _files ;;
+    dir) _files -/ ;;
+    *) compadd -- "${candidates[@]}" ;;
+    esac
+}
+compdef _%[2]s %[1]s
+`,
+
+	"fish": `# fish completion for %[1]s
+function __%[2]s_complete
+    set -l args (commandline -opc)
+    set -e args[1]
+    set -l cur (commandline -ct)
+    set -l out (%[1]s __complete $args "$cur" 2>/dev/null)
+    set -l directive $out[1]
+    set -e out[1]
+
+    switch "$directive"
+        case file
+            __fish_complete_path "$cur"
+        case dir
+            __fish_complete_directories "$cur"
+        case '*'
+            printf '%%s\n' $out
+    end
+end
+complete -c %[1]s -f -a '(__%[2]s_complete)'
+`,
+
+	"powershell": `# powershell completion for %[1]s
+Register-ArgumentCompleter -Native -CommandName '%[1]s' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') {
+        $words += '""'
+    }
+    $out = @(& '%[1]s' __complete @words 2>$null)
+    if ($out.Count -eq 0) {
+        return
+    }
+
+    # Returning nothing falls back to completing paths.
+    $directive = $out[0]
+    if ($directive -eq 'file' -or $directive -eq 'dir') {
+        return
+    }
+    $out | Select-Object -Skip 1 | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`,
+}
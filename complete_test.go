@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testCopySpec is a hand-written spec with arguments and a Complete method.
+type testCopySpec struct {
+	cmd  *Cmd
+	opt  struct{ Format string }
+	args struct {
+		src  string
+		dest []string
+	}
+}
+
+func (t *testCopySpec) Run(ctx context.Context) (interface{}, error) { return nil, nil }
+
+func (t *testCopySpec) Complete(args []string, toComplete string) []string {
+	return []string{"host-a:", "host-b:", "other:"}
+}
+
+func (t *testCopySpec) Cmd() *Cmd {
+	if t.cmd != nil {
+		return t.cmd
+	}
+	t.cmd = &Cmd{
+		RawName: "Copy",
+		RawDoc:  "Copy files.\n\nAliases: cp\nValidate src: file",
+		Args: []*Arg{
+			{Name: "src", Type: "string", Value: &t.args.src},
+			{Name: "dest", Type: "[]string", Variadic: true, Value: &t.args.dest},
+		},
+		Opts: []*Opt{
+			{Key: []string{"Format"}, Value: &t.opt.Format, Short: "f", Tag: `validate:"oneof=json yaml"`},
+		},
+	}
+	Enrich(t.cmd)
+	return t.cmd
+}
+
+func TestComplete(t *testing.T) {
+	b := Cobra{}
+	b.Use = "app"
+	for _, spec := range []Spec{&testSpec{}, &testCopySpec{}} {
+		cmd := b.Add(spec)
+		opts := spec.Cmd().Opts
+		b.SetRunner(cmd, spec, NewLoader(opts, PFlags(cmd.Flags(), opts, DotKey)))
+	}
+	b.AddCompletion()
+
+	tests := []struct {
+		words     []string
+		directive string
+		expect    []string
+	}{
+		{[]string{""}, "values", []string{"completion", "copy", "cp", "server"}},
+		{[]string{"c"}, "values", []string{"completion", "copy", "cp"}},
+		{[]string{"server", ""}, "values", []string{"run"}},
+		{[]string{"server", "run", "--"}, "values", []string{"--name", "--port"}},
+		{[]string{"copy", "--format", ""}, "values", []string{"json", "yaml"}},
+		{[]string{"copy", "-f", "j"}, "values", []string{"json"}},
+		{[]string{"copy", "--format", "=", "y"}, "values", []string{"yaml"}},
+		{[]string{"copy", "--format=y"}, "values", []string{"--format=yaml"}},
+		{[]string{"cp", ""}, "file", nil},
+		{[]string{"copy", "--format", "json", "a.txt", "ho"}, "values", []string{"host-a:", "host-b:"}},
+	}
+
+	for _, test := range tests {
+		directive, got := b.complete(test.words)
+		if directive != test.directive || !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("completing %q: expected %s %v, got %s %v",
+				test.words, test.directive, test.expect, directive, got)
+		}
+	}
+
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		out := &bytes.Buffer{}
+		b.SetOutput(out)
+		b.SetArgs([]string{"completion", shell})
+		if err := b.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "__complete") {
+			t.Errorf("expected %s script to call __complete", shell)
+		}
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	spec := &testCopySpec{}
+	spec.Cmd()
+	spec.args.src = "does-not-exist.txt"
+
+	err := Validate(spec)
+	expect := `invalid src: file "does-not-exist.txt" doesn't exist`
+	if err == nil || err.Error() != expect {
+		t.Errorf("expected %q, got %v", expect, err)
+	}
+}
+
+func TestCompletionCollision(t *testing.T) {
+	testBuiltinCollision(t, "completion")
+	testBuiltinCollision(t, "__complete")
+}
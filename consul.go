@@ -1,30 +1,104 @@
 package cli
 
-func Consul() Provider {
-	return &consul{}
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
 
+// ConsulOpts describes options related to loading options
+// from Consul's KV store.
+type ConsulOpts struct {
+	// Addr is the address of the Consul HTTP API.
+	// Defaults to $CONSUL_HTTP_ADDR, or else "http://127.0.0.1:8500".
+	Addr string
+	// Prefix is the KV key prefix to load, e.g. "myapp/".
+	// A trailing "/" is added if missing, so that "myapp" doesn't load
+	// sibling keys such as "myapp2/addr". Keys under the prefix are mapped to option keys by splitting
+	// on "/", so "myapp/server/addr" sets the "server.addr" option.
+	Prefix string
+	// Token is an ACL token. Defaults to $CONSUL_HTTP_TOKEN.
+	Token string
+	// Timeout is the timeout of the request to Consul.
+	// Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// Consul loads options from Consul's KV store,
+// using the Consul HTTP API.
+func Consul(opts ConsulOpts) Provider {
+	return &consul{opts}
 }
 
 type consul struct {
+	opts ConsulOpts
+}
+
+func (c *consul) String() string {
+	return "consul"
+}
+
+// consulKV is an entry in the response from Consul's KV API.
+type consulKV struct {
+	Key string
+	// Value is base64 encoded in the JSON response,
+	// which encoding/json decodes into []byte.
+	Value []byte
 }
 
 func (c *consul) Provide(l *Loader) error {
-	val := l.Get([]string{"consul"})
-	if val == nil {
+	addr := c.opts.Addr
+	if addr == "" {
+		addr = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if addr == "" {
+		addr = "http://127.0.0.1:8500"
+	}
+
+	token := c.opts.Token
+	if token == "" {
+		token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+
+	timeout := c.opts.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	// Consul keys don't start with "/".
+	prefix := strings.TrimPrefix(c.opts.Prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	u := httpAddr(addr) + "/v1/kv/" + prefix + "?recurse=true"
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return fmt.Errorf("consul: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+
+	var kvs []consulKV
+	err = requestJSON(req, timeout, &kvs)
+	if err == errNotFound {
+		// No keys exist under the prefix.
 		return nil
 	}
-	_ = val
-	return nil
+	if err != nil {
+		return fmt.Errorf("consul: %v", err)
+	}
 
-	/*
-	   conf, ok := val.(*ConsulConfig)
-	   if !ok {
-	     return fmt.Errorf(`"consul" opt must be an instance of *ConsulCOnfig, but got %T`, val)
-	   }
-
-	   conn, err := Connect(conf)
-	   if err != nil {
-	     return fmt.Errorf("connecting to consul: %v", err)
-	   }
-	*/
+	for _, kv := range kvs {
+		key := splitPath(kv.Key, prefix)
+		// Skip the prefix itself, and "folders", which have no value.
+		if key == nil || kv.Value == nil {
+			continue
+		}
+		l.SetFrom(key, string(kv.Value), kv.Key)
+	}
+	return nil
 }
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConsul(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/myapp/" || r.URL.Query().Get("recurse") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		// Values are base64 encoded: ":9090" and "5"
+		w.Write([]byte(`[
+			{"Key": "myapp/", "Value": null},
+			{"Key": "myapp/server/addr", "Value": "OjkwOTA="},
+			{"Key": "myapp/server/workers", "Value": "NQ=="}
+		]`))
+	}))
+	defer srv.Close()
+
+	var addr string
+	var workers int
+	opts := []*Opt{
+		{Key: []string{"Server", "Addr"}, Value: &addr},
+		{Key: []string{"Server", "Workers"}, Value: &workers},
+	}
+
+	l := NewLoader(opts, Consul(ConsulOpts{
+		Addr:   srv.URL,
+		Prefix: "myapp/",
+		Token:  "secret",
+	}))
+	l.Load()
+
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+	if addr != ":9090" || workers != 5 {
+		t.Errorf("unexpected values %q %d", addr, workers)
+	}
+	if loc := l.Source([]string{"server", "addr"}).Location; loc != "myapp/server/addr" {
+		t.Errorf("unexpected location %q", loc)
+	}
+
+	// A missing prefix is not an error.
+	l = NewLoader(opts, Consul(ConsulOpts{Addr: srv.URL, Prefix: "other/"}))
+	l.Load()
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+
+	// A bad token is.
+	l = NewLoader(opts, Consul(ConsulOpts{Addr: srv.URL, Prefix: "myapp/", Token: "bad"}))
+	l.Load()
+	if errs := l.Errors(); errs == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConsulPrefix(t *testing.T) {
+	// Consul lists every key which starts with the requested prefix.
+	// Values are base64 encoded: ":9090", ":1", and ":2".
+	keys := map[string]string{
+		"app/addr":         "OjkwOTA=",
+		"app2/addr":        "OjE=",
+		"application/addr": "OjI=",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		var list []string
+		for k, v := range keys {
+			if strings.HasPrefix(k, prefix) {
+				list = append(list, fmt.Sprintf(`{"Key": %q, "Value": %q}`, k, v))
+			}
+		}
+		if list == nil {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(list, ","))
+	}))
+	defer srv.Close()
+
+	for _, prefix := range []string{"app", "app/", "/app"} {
+		var addr string
+		opts := []*Opt{
+			{Key: []string{"addr"}, Value: &addr},
+		}
+		l := NewLoader(opts, Consul(ConsulOpts{Addr: srv.URL, Prefix: prefix}))
+		l.Strict = true
+		l.Load()
+
+		if errs := l.Errors(); errs != nil {
+			t.Fatal(prefix, errs)
+		}
+		if addr != ":9090" {
+			t.Errorf("prefix %q: unexpected addr %q", prefix, addr)
+		}
+	}
+}
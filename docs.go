@@ -0,0 +1,423 @@
+package cli
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DocOpts describes options related to generating reference
+// documentation for the commands and options of an app.
+type DocOpts struct {
+	// Format is one of "markdown", "html", or "man".
+	// Defaults to "markdown".
+	Format string
+	// AppName is the name of the app, which is the root of
+	// command paths, e.g. "app server run".
+	AppName string
+	// EnvPrefix is prepended to environment variable names,
+	// e.g. the app name. See EnvWith.
+	EnvPrefix string
+	// KeyFunc formats flag names. Defaults to DotKey.
+	KeyFunc KeyFunc
+}
+
+// Docs writes reference documentation for the given commands to "w"
+// as a single document in the format described by "d": Markdown,
+// a single-page HTML reference, or a man page.
+//
+// Each command is documented with its synopsis, doc, usage, aliases,
+// examples, arguments, and options. Options are documented with
+// their flag, type, default value, environment variable, config file key,
+// and validation rules. Hidden commands and options are omitted.
+func Docs(w io.Writer, specs []Spec, d DocOpts) error {
+	cmds := docCmds(specs, d)
+
+	switch d.Format {
+	case "", "markdown", "md":
+		return docsMarkdown(w, cmds, d)
+	case "html":
+		return docsHTML(w, cmds, d)
+	case "man":
+		return docsMan(w, cmds, d)
+	default:
+		return fmt.Errorf("unknown docs format %q", d.Format)
+	}
+}
+
+// ManPages writes a man page for each command to the directory "dir",
+// named after the command path, e.g. "app-server-run.1", along with a
+// page for the app, e.g. "app.1", which documents all commands.
+func ManPages(dir string, specs []Spec, d DocOpts) error {
+	cmds := docCmds(specs, d)
+
+	err := writeFile(filepath.Join(dir, d.AppName+".1"), func(w io.Writer) error {
+		return docsMan(w, cmds, d)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cmds {
+		name := strings.Replace(c.path, " ", "-", -1) + ".1"
+		err := writeFile(filepath.Join(dir, name), func(w io.Writer) error {
+			return manPage(w, c, d)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// docCmd is a command, with the details needed for documentation.
+type docCmd struct {
+	*Cmd
+	// path is the full command path, e.g. "app server run".
+	path  string
+	usage string
+	opts  []*docOpt
+}
+
+// anchor returns the ID of the command's section in Markdown and HTML.
+func (c *docCmd) anchor() string {
+	return strings.Replace(c.path, " ", "-", -1)
+}
+
+// docOpt is an option, with the names it can be set by.
+type docOpt struct {
+	*Opt
+	flag string
+	typ  string
+	env  string
+	// key is the config file key, e.g. "server.addr".
+	key string
+}
+
+func docCmds(specs []Spec, d DocOpts) []*docCmd {
+	kf := d.KeyFunc
+	if kf == nil {
+		kf = DotKey
+	}
+
+	var cmds []*docCmd
+	for _, spec := range specs {
+		cmd := spec.Cmd()
+		if cmd.Hidden {
+			continue
+		}
+
+		path := strings.TrimSpace(d.AppName + " " + strings.Join(cmd.Path, " "))
+		c := &docCmd{Cmd: cmd, path: path, usage: path}
+		if len(cmd.Opts) > 0 {
+			c.usage += " [flags]"
+		}
+		for _, arg := range cmd.Args {
+			if arg.Variadic {
+				c.usage += " [" + arg.Name + "...]"
+			} else {
+				c.usage += " <" + arg.Name + ">"
+			}
+		}
+
+		for _, opt := range cmd.Opts {
+			if opt.Hidden {
+				continue
+			}
+			flag := "--" + kf(opt.Key)
+			if opt.Short != "" {
+				flag = "-" + opt.Short + ", " + flag
+			}
+			c.opts = append(c.opts, &docOpt{
+				Opt:  opt,
+				flag: flag,
+				typ:  newPflagValue(opt, "").Type(),
+				env:  envName(d.EnvPrefix, opt.Key),
+				key:  DotKey(opt.Key),
+			})
+
+			// Fields of list elements are documented with an "N" placeholder
+			// for the index, e.g. "--backends.N.addr".
+			for _, f := range opt.Fields {
+				key := append(append(opt.Key[:len(opt.Key):len(opt.Key)], "#"), f.Key...)
+				c.opts = append(c.opts, &docOpt{
+					Opt:  f,
+					flag: strings.Replace("--"+kf(key), "#", "N", -1),
+					typ:  f.Type,
+					env:  strings.Replace(envName(d.EnvPrefix, key), "#", "N", -1),
+					key:  DotKey(opt.Key) + "[]." + DotKey(f.Key),
+				})
+			}
+		}
+		cmds = append(cmds, c)
+	}
+	return cmds
+}
+
+// details returns notes about an option, other than its synopsis,
+// such as validation rules, e.g. "Validate: required".
+func (o *docOpt) details() []string {
+	var notes []string
+	if o.Deprecated != "" {
+		notes = append(notes, "Deprecated: "+o.Deprecated)
+	}
+	var rules []string
+	for _, r := range o.Rules {
+		if r.Arg != "" {
+			rules = append(rules, r.Name+"="+r.Arg)
+		} else {
+			rules = append(rules, r.Name)
+		}
+	}
+	if rules != nil {
+		notes = append(notes, "Validate: "+strings.Join(rules, ", "))
+	}
+	return notes
+}
+
+func docsMarkdown(w io.Writer, cmds []*docCmd, d DocOpts) error {
+	if d.AppName != "" {
+		fmt.Fprintf(w, "# %s\n\n", d.AppName)
+	}
+	for _, c := range cmds {
+		fmt.Fprintf(w, "- [%s](#%s)", c.path, c.anchor())
+		if c.Synopsis != "" {
+			fmt.Fprintf(w, ": %s", c.Synopsis)
+		}
+		fmt.Fprintln(w)
+	}
+
+	for _, c := range cmds {
+		fmt.Fprintf(w, "\n## %s\n\n", c.path)
+		for _, p := range []string{c.Synopsis, c.Doc} {
+			if p != "" {
+				fmt.Fprintf(w, "%s\n\n", p)
+			}
+		}
+		fmt.Fprintf(w, "```\n%s\n```\n", c.usage)
+
+		if c.Deprecated != "" {
+			fmt.Fprintf(w, "\n**Deprecated:** %s\n", c.Deprecated)
+		}
+		if len(c.Aliases) > 0 {
+			fmt.Fprintf(w, "\nAliases: `%s`\n", strings.Join(c.Aliases, "`, `"))
+		}
+		if c.Example != "" {
+			fmt.Fprintf(w, "\n### Example\n\n```\n%s\n```\n", c.Example)
+		}
+
+		if len(c.Args) > 0 {
+			fmt.Fprintf(w, "\n### Arguments\n\n")
+			for _, arg := range c.Args {
+				fmt.Fprintf(w, "- `%s` (%s)\n", arg.Name, arg.Type)
+			}
+		}
+
+		if len(c.opts) > 0 {
+			fmt.Fprintf(w, "\n### Options\n\n")
+			fmt.Fprintln(w, "| Flag | Type | Default | Env | Config key | Description |")
+			fmt.Fprintln(w, "| --- | --- | --- | --- | --- | --- |")
+			for _, o := range c.opts {
+				desc := strings.Join(append([]string{o.Synopsis}, o.details()...), " ")
+				fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s |\n",
+					mdCode(o.flag), mdCell(o.typ), mdCode(o.DefaultString),
+					mdCode(o.env), mdCode(o.key), mdCell(strings.TrimSpace(desc)))
+			}
+		}
+	}
+	return nil
+}
+
+// mdCell escapes a string for a Markdown table cell.
+func mdCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+func mdCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + mdCell(s) + "`"
+}
+
+func docsHTML(w io.Writer, cmds []*docCmd, d DocOpts) error {
+	esc := html.EscapeString
+	title := "Command reference"
+	if d.AppName != "" {
+		title = d.AppName + " command reference"
+	}
+
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(w, "<title>%s</title>\n</head>\n<body>\n", esc(title))
+	fmt.Fprintf(w, "<h1>%s</h1>\n<ul>\n", esc(title))
+	for _, c := range cmds {
+		fmt.Fprintf(w, "<li><a href=\"#%s\">%s</a>", esc(c.anchor()), esc(c.path))
+		if c.Synopsis != "" {
+			fmt.Fprintf(w, ": %s", esc(c.Synopsis))
+		}
+		fmt.Fprintf(w, "</li>\n")
+	}
+	fmt.Fprintf(w, "</ul>\n")
+
+	for _, c := range cmds {
+		fmt.Fprintf(w, "<section id=\"%s\">\n<h2>%s</h2>\n", esc(c.anchor()), esc(c.path))
+		for _, p := range append([]string{c.Synopsis}, strings.Split(c.Doc, "\n\n")...) {
+			if p != "" {
+				fmt.Fprintf(w, "<p>%s</p>\n", esc(p))
+			}
+		}
+		fmt.Fprintf(w, "<pre>%s</pre>\n", esc(c.usage))
+
+		if c.Deprecated != "" {
+			fmt.Fprintf(w, "<p><strong>Deprecated:</strong> %s</p>\n", esc(c.Deprecated))
+		}
+		if len(c.Aliases) > 0 {
+			fmt.Fprintf(w, "<p>Aliases: %s</p>\n", esc(strings.Join(c.Aliases, ", ")))
+		}
+		if c.Example != "" {
+			fmt.Fprintf(w, "<h3>Example</h3>\n<pre>%s</pre>\n", esc(c.Example))
+		}
+
+		if len(c.Args) > 0 {
+			fmt.Fprintf(w, "<h3>Arguments</h3>\n<ul>\n")
+			for _, arg := range c.Args {
+				fmt.Fprintf(w, "<li><code>%s</code> (%s)</li>\n", esc(arg.Name), esc(arg.Type))
+			}
+			fmt.Fprintf(w, "</ul>\n")
+		}
+
+		if len(c.opts) > 0 {
+			fmt.Fprintf(w, "<h3>Options</h3>\n<table>\n")
+			fmt.Fprintf(w, "<tr><th>Flag</th><th>Type</th><th>Default</th><th>Env</th><th>Config key</th><th>Description</th></tr>\n")
+			for _, o := range c.opts {
+				desc := esc(o.Synopsis)
+				for _, n := range o.details() {
+					desc += "<br>" + esc(n)
+				}
+				fmt.Fprintf(w, "<tr><td><code>%s</code></td><td>%s</td><td><code>%s</code></td><td><code>%s</code></td><td><code>%s</code></td><td>%s</td></tr>\n",
+					esc(o.flag), esc(o.typ), esc(o.DefaultString), esc(o.env), esc(o.key), desc)
+			}
+			fmt.Fprintf(w, "</table>\n")
+		}
+		fmt.Fprintf(w, "</section>\n")
+	}
+
+	fmt.Fprintf(w, "</body>\n</html>\n")
+	return nil
+}
+
+// docsMan writes a man page for the app, which documents all commands.
+func docsMan(w io.Writer, cmds []*docCmd, d DocOpts) error {
+	fmt.Fprintf(w, ".TH \"%s\" \"1\" \"\" \"%s\"\n", manEscape(strings.ToUpper(d.AppName)), manEscape(d.AppName))
+	fmt.Fprintf(w, ".SH NAME\n%s \\- command reference\n", manEscape(d.AppName))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B %s\ncommand [flags]\n", manEscape(d.AppName))
+	fmt.Fprintf(w, ".SH COMMANDS\n")
+	for _, c := range cmds {
+		fmt.Fprintf(w, ".SS %s\n", manEscape(c.path))
+		manCmd(w, c, func(title string) {
+			fmt.Fprintf(w, ".PP\n.I %s\n", manEscape(title))
+		})
+	}
+	return nil
+}
+
+// manPage writes a man page for a single command.
+func manPage(w io.Writer, c *docCmd, d DocOpts) error {
+	title := strings.ToUpper(c.anchor())
+	fmt.Fprintf(w, ".TH \"%s\" \"1\" \"\" \"%s\"\n", manEscape(title), manEscape(d.AppName))
+	fmt.Fprintf(w, ".SH NAME\n%s", manEscape(c.anchor()))
+	if c.Synopsis != "" {
+		fmt.Fprintf(w, " \\- %s", manEscape(c.Synopsis))
+	}
+	fmt.Fprintln(w)
+	manCmd(w, c, func(title string) {
+		fmt.Fprintf(w, ".SH %s\n", strings.ToUpper(title))
+	})
+	if d.AppName != "" {
+		fmt.Fprintf(w, ".SH SEE ALSO\n.BR %s (1)\n", manEscape(d.AppName))
+	}
+	return nil
+}
+
+// manCmd writes the details of a command, where "section"
+// writes the heading of each part, e.g. "Options".
+func manCmd(w io.Writer, c *docCmd, section func(string)) {
+	section("Synopsis")
+	fmt.Fprintf(w, ".nf\n%s\n.fi\n", manEscape(c.usage))
+
+	if c.Synopsis != "" || c.Doc != "" {
+		section("Description")
+		for _, p := range append([]string{c.Synopsis}, strings.Split(c.Doc, "\n\n")...) {
+			if p != "" {
+				fmt.Fprintf(w, ".PP\n%s\n", manEscape(p))
+			}
+		}
+	}
+	if c.Deprecated != "" {
+		fmt.Fprintf(w, ".PP\nDeprecated: %s\n", manEscape(c.Deprecated))
+	}
+	if len(c.Aliases) > 0 {
+		fmt.Fprintf(w, ".PP\nAliases: %s\n", manEscape(strings.Join(c.Aliases, ", ")))
+	}
+
+	if len(c.Args) > 0 {
+		section("Arguments")
+		for _, arg := range c.Args {
+			fmt.Fprintf(w, ".TP\n.B %s\n%s\n", manEscape(arg.Name), manEscape(arg.Type))
+		}
+	}
+
+	if len(c.opts) > 0 {
+		section("Options")
+		for _, o := range c.opts {
+			fmt.Fprintf(w, ".TP\n\\fB%s\\fR \\fI%s\\fR\n", manEscape(o.flag), manEscape(o.typ))
+			if o.Synopsis != "" {
+				fmt.Fprintf(w, "%s\n", manEscape(o.Synopsis))
+			}
+			var notes []string
+			if o.DefaultString != "" {
+				notes = append(notes, "Default: "+o.DefaultString)
+			}
+			notes = append(notes, "Env: "+o.env, "Config key: "+o.key)
+			notes = append(notes, o.details()...)
+			for _, n := range notes {
+				fmt.Fprintf(w, ".br\n%s\n", manEscape(n))
+			}
+		}
+	}
+
+	if c.Example != "" {
+		section("Example")
+		fmt.Fprintf(w, ".nf\n%s\n.fi\n", manEscape(c.Example))
+	}
+}
+
+// manEscape escapes text for roff, so that dashes, backslashes,
+// and lines starting with a dot are written literally.
+func manEscape(s string) string {
+	s = strings.Replace(s, `\`, `\e`, -1)
+	s = strings.Replace(s, "-", `\-`, -1)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func ExampleDocs() {
+	spec := &testSpec{opt: testOpt{Name: "default", Port: 8080}}
+	spec.Cmd().Opts[1].Rules = []Rule{{Name: "port"}}
+
+	Docs(os.Stdout, []Spec{spec}, DocOpts{AppName: "app", EnvPrefix: "app"})
+	// Output:
+	// # app
+	//
+	// - [app server run](#app-server-run): Run a server.
+	//
+	// ## app server run
+	//
+	// Run a server.
+	//
+	// ```
+	// app server run [flags]
+	// ```
+	//
+	// ### Options
+	//
+	// | Flag | Type | Default | Env | Config key | Description |
+	// | --- | --- | --- | --- | --- | --- |
+	// | `--name` | string | `default` | `APP_NAME` | `name` | Server name. |
+	// | `--port` | int | `8080` | `APP_PORT` | `port` | Validate: port |
+}
+
+func TestDocsFormats(t *testing.T) {
+	spec := &testSpec{opt: testOpt{Name: "default", Port: 8080}}
+	specs := []Spec{spec}
+	d := DocOpts{AppName: "app", EnvPrefix: "app"}
+
+	b := &bytes.Buffer{}
+	d.Format = "html"
+	if err := Docs(b, specs, d); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<section id="app-server-run">`, "<code>--port</code>", "<code>APP_PORT</code>"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expected HTML to contain %q", s)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "cli-docs-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d.Format = "man"
+	if err := ManPages(dir, specs, d); err != nil {
+		t.Fatal(err)
+	}
+	page, err := ioutil.ReadFile(filepath.Join(dir, "app-server-run.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`.TH "APP\-SERVER\-RUN" "1"`, `\fB\-\-name\fR \fIstring\fR`, "Env: APP_NAME"} {
+		if !strings.Contains(string(page), s) {
+			t.Errorf("expected man page to contain %q", s)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.1")); err != nil {
+		t.Error(err)
+	}
+
+	d.Format = "pdf"
+	if Docs(b, specs, d) == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestDocsCollision(t *testing.T) {
+	testBuiltinCollision(t, "docs")
+}
@@ -0,0 +1,469 @@
+package cli
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"io"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DumpOpts describes options related to writing the effective
+// option values, e.g. for debugging, bug reports, or generating
+// a config file.
+type DumpOpts struct {
+	// Format is one of "yaml", "json", "toml", "env", or "flags".
+	// Defaults to "yaml".
+	Format string
+	// ExcludeDefaults omits options whose value is equal to the default value.
+	ExcludeDefaults bool
+	// OmitDocs omits the option docs, which are normally written as comments.
+	// JSON doesn't support comments, so docs are always omitted from JSON.
+	OmitDocs bool
+	// Prefix is prepended to environment variable names in the "env" format,
+	// e.g. the app name.
+	Prefix string
+	// KeyFunc formats flag names in the "flags" format. Defaults to DotKey.
+	KeyFunc KeyFunc
+}
+
+// Dump is like the Dump function, but writes the values of the loader's
+// options, which is safe while the loader may be reloading.
+func (l *Loader) Dump(w io.Writer, d DumpOpts) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return Dump(w, l.opts, d)
+}
+
+// Dump writes the current values of the given options to "w"
+// in the format described by "d". Options which don't have
+// a representable value, such as an io.Writer, are skipped.
+// The YAML, JSON, and TOML formats can be loaded as config files,
+// e.g. by Layered.
+func Dump(w io.Writer, opts []*Opt, d DumpOpts) error {
+	var entries []*dumpEntry
+	for _, opt := range opts {
+		val, ok := dumpValue(opt)
+		if !ok {
+			continue
+		}
+		if d.ExcludeDefaults && reflect.DeepEqual(deref(opt.ref(false)), opt.DefaultValue) {
+			continue
+		}
+		if opt.Unit != "" {
+			val = formatUnit(opt.Unit, val)
+		}
+		if opt.Sensitive {
+			val = Redacted
+		}
+		e := &dumpEntry{opt: opt, val: val}
+		if !d.OmitDocs {
+			e.doc = strings.TrimSpace(opt.Synopsis + "\n" + opt.Doc)
+		}
+		entries = append(entries, e)
+	}
+
+	switch d.Format {
+	case "", "yaml":
+		return dumpYAML(w, buildDumpTree(entries), 0)
+	case "json":
+		return dumpJSON(w, entries)
+	case "toml":
+		return dumpTOML(w, buildDumpTree(entries), nil)
+	case "env":
+		return dumpEnv(w, entries, d.Prefix)
+	case "flags":
+		kf := d.KeyFunc
+		if kf == nil {
+			kf = DotKey
+		}
+		return dumpFlags(w, entries, kf)
+	default:
+		return fmt.Errorf("unknown dump format %q", d.Format)
+	}
+}
+
+type dumpEntry struct {
+	opt *Opt
+	val interface{}
+	doc string
+}
+
+// dumpNode is a node in a tree of option keys, used to write
+// nested formats (YAML, TOML) in the same order as the options.
+type dumpNode struct {
+	name     string
+	entry    *dumpEntry
+	children []*dumpNode
+}
+
+func (n *dumpNode) child(name string) *dumpNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &dumpNode{name: name}
+	n.children = append(n.children, c)
+	return c
+}
+
+func buildDumpTree(entries []*dumpEntry) *dumpNode {
+	root := &dumpNode{}
+	for _, e := range entries {
+		n := root
+		for _, k := range e.opt.Key {
+			n = n.child(strings.ToLower(k))
+		}
+		n.entry = e
+	}
+	return root
+}
+
+func dumpYAML(w io.Writer, n *dumpNode, depth int) error {
+	indent := strings.Repeat("  ", depth)
+
+	for _, c := range n.children {
+		if c.entry == nil {
+			fmt.Fprintf(w, "%s%s:\n", indent, c.name)
+			if err := dumpYAML(w, c, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		writeComment(w, indent, c.entry.doc)
+
+		b, err := yaml.Marshal(c.entry.val)
+		if err != nil {
+			return fmt.Errorf("marshaling %s: %v", DotKey(c.entry.opt.Key), err)
+		}
+		s := strings.TrimSuffix(string(b), "\n")
+
+		lines := strings.Split(s, "\n")
+		if isCollection(c.entry.val) {
+			fmt.Fprintf(w, "%s%s:\n", indent, c.name)
+			for _, line := range lines {
+				fmt.Fprintf(w, "%s  %s\n", indent, line)
+			}
+		} else {
+			// Multi-line strings are written as block scalars,
+			// which need to be indented to the current depth.
+			fmt.Fprintf(w, "%s%s: %s\n", indent, c.name, lines[0])
+			for _, line := range lines[1:] {
+				fmt.Fprintf(w, "%s%s\n", indent, line)
+			}
+		}
+	}
+	return nil
+}
+
+// isCollection returns true if v is a non-empty list or map.
+func isCollection(v interface{}) bool {
+	switch x := v.(type) {
+	case []interface{}:
+		return len(x) > 0
+	case map[string]interface{}:
+		return len(x) > 0
+	}
+	return false
+}
+
+func dumpJSON(w io.Writer, entries []*dumpEntry) error {
+	root := map[string]interface{}{}
+	for _, e := range entries {
+		m := root
+		key := e.opt.Key
+		for _, k := range key[:len(key)-1] {
+			k = strings.ToLower(k)
+			sub, ok := m[k].(map[string]interface{})
+			if !ok {
+				sub = map[string]interface{}{}
+				m[k] = sub
+			}
+			m = sub
+		}
+		m[strings.ToLower(key[len(key)-1])] = e.val
+	}
+
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func dumpTOML(w io.Writer, n *dumpNode, path []string) error {
+	// TOML requires all the values of a table to be written
+	// before any sub-tables, so leaves are written first.
+	for _, c := range n.children {
+		if c.entry == nil {
+			continue
+		}
+		writeComment(w, "", c.entry.doc)
+		fmt.Fprintf(w, "%s = %s\n", c.name, tomlValue(c.entry.val))
+	}
+
+	for _, c := range n.children {
+		if c.entry != nil {
+			continue
+		}
+		sub := append(path[:len(path):len(path)], c.name)
+		fmt.Fprintf(w, "\n[%s]\n", strings.Join(sub, "."))
+		if err := dumpTOML(w, c, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tomlValue formats a value as an inline TOML value.
+func tomlValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return `""`
+	case string:
+		return strconv.Quote(x)
+	case []interface{}:
+		var items []string
+		for _, i := range x {
+			items = append(items, tomlValue(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		var items []string
+		for _, k := range sortedKeys(x) {
+			items = append(items, fmt.Sprintf("%s = %s", strconv.Quote(k), tomlValue(x[k])))
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func dumpEnv(w io.Writer, entries []*dumpEntry, prefix string) error {
+	for _, e := range entries {
+		key := e.opt.Key
+		if prefix != "" {
+			key = append([]string{prefix}, key...)
+		}
+		writeComment(w, "", e.doc)
+		if elems, ok := elemValues(e); ok {
+			for _, el := range elems {
+				k := append(key[:len(key):len(key)], el.key...)
+				fmt.Fprintf(w, "%s=%s\n", strings.ToUpper(UnderscoreKey(k)), shellQuote(flatValue(el.val)))
+			}
+			continue
+		}
+		fmt.Fprintf(w, "%s=%s\n", strings.ToUpper(UnderscoreKey(key)), shellQuote(flatValue(e.val)))
+	}
+	return nil
+}
+
+func dumpFlags(w io.Writer, entries []*dumpEntry, kf KeyFunc) error {
+	for _, e := range entries {
+		writeComment(w, "", e.doc)
+		if elems, ok := elemValues(e); ok {
+			for _, el := range elems {
+				k := append(e.opt.Key[:len(e.opt.Key):len(e.opt.Key)], el.key...)
+				fmt.Fprintf(w, "--%s=%s\n", kf(k), shellQuote(flatValue(el.val)))
+			}
+			continue
+		}
+		fmt.Fprintf(w, "--%s=%s\n", kf(e.opt.Key), shellQuote(flatValue(e.val)))
+	}
+	return nil
+}
+
+type elemValue struct {
+	// key is relative to the option, e.g. ["0", "addr"].
+	key []string
+	val interface{}
+}
+
+// elemValues returns the values of the fields of each element
+// of a list of structs, which are written as indexed variables
+// or flags, e.g. "--backends.0.addr". Returns false if the entry
+// isn't a list of structs.
+func elemValues(e *dumpEntry) ([]elemValue, bool) {
+	list, ok := e.val.([]interface{})
+	if !ok || len(e.opt.Fields) == 0 {
+		return nil, false
+	}
+	var elems []elemValue
+	for i, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		walkPaths(m, []string{strconv.Itoa(i)}, func(key []string, v interface{}) {
+			elems = append(elems, elemValue{key, v})
+		})
+	}
+	return elems, true
+}
+
+// flatValue formats a value as a single string, e.g. for
+// an environment variable or flag. Lists are joined by commas,
+// maps are written as comma-separated key=value pairs.
+func flatValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		var items []string
+		for _, i := range x {
+			items = append(items, flatValue(i))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		var items []string
+		for _, k := range sortedKeys(x) {
+			items = append(items, k+"="+flatValue(x[k]))
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// shellQuote quotes a string if it contains characters
+// which would be interpreted by a shell.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(s, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func writeComment(w io.Writer, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(w, "%s# %s\n", indent, line)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dumpValue returns the current value of an option, converted to
+// plain types (strings, numbers, lists, maps) which can be written
+// to any dump format. Returns false if the value can't be represented,
+// e.g. an io.Writer.
+func dumpValue(opt *Opt) (interface{}, bool) {
+	v := reflect.ValueOf(opt.ref(false))
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, false
+	}
+	return plainValue(v.Elem())
+}
+
+var (
+	durationType      = reflect.TypeOf(time.Duration(0))
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func plainValue(v reflect.Value) (interface{}, bool) {
+	switch {
+	case v.Type() == durationType:
+		return v.Interface().(time.Duration).String(), true
+	case v.Type() == timeType:
+		return v.Interface().(time.Time).Format(time.RFC3339), true
+	case v.Type() == urlType:
+		u := v.Interface().(url.URL)
+		return u.String(), true
+	case v.Type().Implements(textMarshalerType),
+		v.CanAddr() && v.Addr().Type().Implements(textMarshalerType):
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, true
+		}
+		if !v.Type().Implements(textMarshalerType) {
+			v = v.Addr()
+		}
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, false
+		}
+		return string(b), true
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return v.String(), true
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), true
+		}
+		list := []interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			x, ok := plainValue(v.Index(i))
+			if !ok {
+				return nil, false
+			}
+			list = append(list, x)
+		}
+		return list, true
+
+	case reflect.Map:
+		m := map[string]interface{}{}
+		for _, k := range v.MapKeys() {
+			x, ok := plainValue(v.MapIndex(k))
+			if !ok {
+				return nil, false
+			}
+			m[fmt.Sprint(k.Interface())] = x
+		}
+		return m, true
+
+	case reflect.Struct:
+		m := map[string]interface{}{}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			x, ok := plainValue(v.Field(i))
+			if !ok {
+				continue
+			}
+			m[strings.ToLower(f.Name)] = x
+		}
+		return m, true
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, true
+		}
+		return plainValue(v.Elem())
+	}
+	return nil, false
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func ExampleDump() {
+	type opt struct {
+		Name    string
+		Timeout time.Duration
+		Hosts   []string
+		Tags    map[string]string
+	}
+	o := opt{
+		Name:    "example",
+		Timeout: time.Second,
+		Hosts:   []string{"one", "two"},
+		Tags:    map[string]string{"env": "prod"},
+	}
+
+	opts := []*Opt{
+		{Key: []string{"Name"}, Value: &o.Name, DefaultValue: o.Name, Synopsis: "Server name."},
+		{Key: []string{"Server", "Timeout"}, Value: &o.Timeout, DefaultValue: 5 * time.Second},
+		{Key: []string{"Server", "Hosts"}, Value: &o.Hosts},
+		{Key: []string{"Tags"}, Value: &o.Tags},
+	}
+
+	Dump(os.Stdout, opts, DumpOpts{Format: "yaml"})
+	Dump(os.Stdout, opts, DumpOpts{Format: "toml", ExcludeDefaults: true})
+	Dump(os.Stdout, opts, DumpOpts{Format: "env", Prefix: "app", OmitDocs: true})
+	// Output:
+	// # Server name.
+	// name: example
+	// server:
+	//   timeout: 1s
+	//   hosts:
+	//     - one
+	//     - two
+	// tags:
+	//   env: prod
+	// tags = { "env" = "prod" }
+	//
+	// [server]
+	// timeout = "1s"
+	// hosts = ["one", "two"]
+	// APP_NAME=example
+	// APP_SERVER_TIMEOUT=1s
+	// APP_SERVER_HOSTS=one,two
+	// APP_TAGS=env=prod
+}
+
+func TestDumpStructList(t *testing.T) {
+	backends := []testBackend{{Addr: "a:80", MaxWeight: 2}}
+	opts := []*Opt{testBackendOpt(&backends)}
+
+	b := &bytes.Buffer{}
+	err := Dump(b, opts, DumpOpts{Format: "flags", OmitDocs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "--backends.0.addr=a:80\n--backends.0.maxweight=2\n--backends.0.tls.cert=''\n"
+	if b.String() != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, b.String())
+	}
+}
+
+type roundTripOpt struct {
+	Name     string
+	Debug    bool
+	Timeout  time.Duration
+	Hosts    []string
+	Tags     map[string]string
+	Limits   map[string]int
+	Backends []testBackend
+}
+
+func roundTripOpts(o *roundTripOpt) []*Opt {
+	return []*Opt{
+		{Key: []string{"Name"}, Value: &o.Name},
+		{Key: []string{"Server", "Debug"}, Value: &o.Debug},
+		{Key: []string{"Server", "Timeout"}, Value: &o.Timeout},
+		{Key: []string{"Server", "Hosts"}, Value: &o.Hosts},
+		{Key: []string{"Tags"}, Value: &o.Tags},
+		{Key: []string{"Limits"}, Value: &o.Limits},
+		testBackendOpt(&o.Backends),
+	}
+}
+
+// TestDumpRoundTrip checks that dumped config files can be loaded.
+func TestDumpRoundTrip(t *testing.T) {
+	expect := roundTripOpt{
+		Name:     "example",
+		Debug:    true,
+		Timeout:  time.Second,
+		Hosts:    []string{"one", "two"},
+		Tags:     map[string]string{"env": "prod", "team": "a b"},
+		Limits:   map[string]int{"cpu": 2},
+		Backends: []testBackend{{Addr: "a:80", MaxWeight: 2}},
+	}
+
+	for _, format := range []string{"yaml", "json", "toml"} {
+		b := &bytes.Buffer{}
+		err := Dump(b, roundTripOpts(&expect), DumpOpts{Format: format})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := "config." + format
+		fsys := fstest.MapFS{path: {Data: b.Bytes()}}
+		var got roundTripOpt
+		l := NewLoader(roundTripOpts(&got), Layered(FileOpts{Paths: []string{path}, FS: fsys}))
+		l.Load()
+
+		if errs := l.Errors(); errs != nil {
+			t.Fatalf("%s: %v\n%s", format, errs, b)
+		}
+		if !reflect.DeepEqual(got, expect) {
+			t.Errorf("%s: expected %+v, got %+v\n%s", format, expect, got, b)
+		}
+	}
+}
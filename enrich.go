@@ -34,9 +34,6 @@ func enrichOpt(opt *Opt) {
 		//      there's no Opt for BarBAZ to be parsed. Probably should rely
 		//      on struct tags, on a runtime builder, and/or parse struct field
 		//      docs in the code generator.
-		//
-		//      Also, struct tags can be reflected at runtime here in ParseOptDetail,
-		//      if desired.
 		//if strings.HasPrefix(line, "Name: ") {
 		//det.Name = strings.TrimPrefix(line, "Name: ")
 		//}
@@ -45,6 +42,14 @@ func enrichOpt(opt *Opt) {
 		case line == opt.Synopsis:
 		case line == "Hidden":
 			opt.Hidden = true
+		case line == "Sensitive":
+			opt.Sensitive = true
+		case line == "Count":
+			opt.Count = true
+		case strings.HasPrefix(line, "Unit: "):
+			opt.Unit = strings.TrimPrefix(line, "Unit: ")
+		case strings.HasPrefix(line, "Validate: "):
+			opt.Rules = append(opt.Rules, parseRules(strings.TrimPrefix(line, "Validate: "))...)
 		case strings.HasPrefix(line, "Deprecated: "):
 			opt.Deprecated = strings.TrimPrefix(line, "Deprecated: ")
 		default:
@@ -53,6 +58,25 @@ func enrichOpt(opt *Opt) {
 	}
 	opt.Doc = strings.TrimSpace(strings.Join(lines, "\n"))
 
+	// Struct tags are an alternative to doc annotations.
+	tag := reflect.StructTag(opt.Tag)
+	if tag.Get("sensitive") == "true" {
+		opt.Sensitive = true
+	}
+	if tag.Get("count") == "true" {
+		opt.Count = true
+	}
+	opt.Rules = append(opt.Rules, parseRules(tag.Get("validate"))...)
+	if unit := tag.Get("unit"); unit != "" {
+		opt.Unit = unit
+	}
+
+	// Options with a pointer parent don't have a generated default value,
+	// since the parent may be nil.
+	if opt.Ref != nil && opt.DefaultValue == nil {
+		opt.DefaultValue = deref(opt.Ref(false))
+	}
+
 	switch {
 	case opt.DefaultValue == os.Stderr:
 		opt.DefaultString = "os.Stderr"
@@ -60,12 +84,42 @@ func enrichOpt(opt *Opt) {
 		opt.DefaultString = "os.Stdout"
 	case opt.DefaultValue == nil:
 		opt.DefaultString = ""
+		if opt.Ref != nil {
+			opt.DefaultString = "unset"
+		}
 		// TODO not super happy about including reflect just for this.
 	case reflect.TypeOf(opt.DefaultValue).Kind() == reflect.Ptr:
-		opt.DefaultString = ""
+		v := reflect.ValueOf(opt.DefaultValue)
+		s, isStringer := opt.DefaultValue.(fmt.Stringer)
+		switch {
+		case v.IsNil():
+			// A nil pointer option, e.g. *int, means "not provided".
+			opt.DefaultString = "unset"
+		case isStringer:
+			// e.g. *url.URL
+			opt.DefaultString = s.String()
+		case opt.Unit != "":
+			opt.DefaultString = formatUnit(opt.Unit, v.Elem().Interface())
+		case v.Elem().Kind() == reflect.Struct:
+			opt.DefaultString = ""
+		default:
+			opt.DefaultString = fmt.Sprintf("%v", v.Elem().Interface())
+		}
+	case opt.Unit != "":
+		opt.DefaultString = formatUnit(opt.Unit, opt.DefaultValue)
+	case collectionKind(opt) != reflect.Invalid:
+		opt.DefaultString = collectionString(opt.DefaultValue)
 	default:
 		opt.DefaultString = fmt.Sprintf("%v", opt.DefaultValue)
 	}
+
+	if opt.Sensitive && opt.DefaultString != "" {
+		opt.DefaultString = Redacted
+	}
+
+	for _, f := range opt.Fields {
+		enrichOpt(f)
+	}
 }
 
 // enrichCmd parses a command's doc string for additional information.
@@ -107,9 +161,31 @@ func enrichCmd(cmd *Cmd) {
 			cmd.Hidden = true
 		case strings.HasPrefix(line, "Aliases: "):
 			cmd.Aliases = strings.Split(strings.TrimPrefix(line, "Aliases: "), " ")
+		case enrichArg(cmd, line):
 		default:
 			lines = append(lines, line)
 		}
 	}
 	cmd.Doc = strings.TrimSpace(strings.Join(lines, "\n"))
 }
+
+// enrichArg parses an argument annotation from a line of a command's doc,
+// e.g. "Validate src: file". Returns false if the line isn't an annotation
+// of one of the command's arguments.
+func enrichArg(cmd *Cmd, line string) bool {
+	if !strings.HasPrefix(line, "Validate ") {
+		return false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(line, "Validate "), ":", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	name := strings.TrimSpace(parts[0])
+	for _, arg := range cmd.Args {
+		if arg.Name == name {
+			arg.Rules = append(arg.Rules, parseRules(parts[1])...)
+			return true
+		}
+	}
+	return false
+}
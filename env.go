@@ -1,38 +1,321 @@
 package cli
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
+// EnvOpts describes options related to loading option values
+// from environment variables.
+type EnvOpts struct {
+	// Prefix is prepended to variable names, e.g. the app name.
+	Prefix string
+	// Separator splits list and map values. Defaults to ",".
+	Separator string
+	// LookupEnv looks up an environment variable. Defaults to os.LookupEnv.
+	// Set LookupEnv and Environ to load values from somewhere other than
+	// the process environment, e.g. a map in a test.
+	LookupEnv func(key string) (string, bool)
+	// Environ lists all environment variables as "key=value".
+	// Used for map keys and strict mode. Defaults to os.Environ.
+	Environ func() []string
+	// FS is the file system "_FILE" variables are read from, see EnvWith.
+	// Defaults to the OS file system.
+	FS fs.FS
+}
+
 // Env loads option values from environment variables
 // with the given prefix. The prefix and option keys
-// are converted to uppercase.
+// are converted to uppercase. See EnvWith.
 func Env(prefix string) Provider {
-	return &env{prefix}
+	return EnvWith(EnvOpts{Prefix: prefix})
+}
+
+// EnvWith loads option values from environment variables.
+// Variable names are made of the prefix and option key, converted
+// to uppercase and joined by underscores, e.g. Server.Addr with
+// prefix "app" is APP_SERVER_ADDR.
+//
+// Lists may be given as a single variable, split by the separator,
+// e.g. APP_HOSTS=a,b, or as indexed variables starting at zero,
+// e.g. APP_HOSTS_0=a and APP_HOSTS_1=b.
+//
+// Maps may be given as a single variable of key=value pairs,
+// e.g. APP_TAGS=env=prod,team=web, and/or one variable per key,
+// e.g. APP_TAGS_ENV=prod. Keys from variable names are lowercased.
+//
+// Lists of structs may be given as indexed variables for each field,
+// e.g. APP_BACKENDS_0_ADDR and APP_BACKENDS_0_WEIGHT, or as a single
+// variable containing a JSON list, e.g. APP_BACKENDS=[{"addr": "a"}].
+//
+// If a variable isn't set, but the same variable with a "_FILE" suffix is,
+// e.g. APP_DB_PASSWORD_FILE=/run/secrets/db, the value is read from that
+// file, which is useful for Docker and Kubernetes secrets.
+func EnvWith(opts EnvOpts) Provider {
+	if opts.Separator == "" {
+		opts.Separator = ","
+	}
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	if opts.Environ == nil {
+		opts.Environ = os.Environ
+	}
+	return &env{opts}
 }
 
 // env loads option values from environment variables.
 type env struct {
-	Prefix string
+	EnvOpts
 }
 
 func (e *env) Provide(l *Loader) error {
-	for _, key := range l.Keys() {
+	known := map[string]bool{}
+	var names []string
+	// prefixes of variables for indexed lists and map keys.
+	var collections []string
+	var errs []error
 
-		var prefixed []string
-		if e.Prefix != "" {
-			prefixed = append([]string{e.Prefix}, key...)
-		} else {
-			prefixed = key
+	for _, opt := range l.opts {
+		k := e.name(opt.Key)
+		known[k] = true
+		known[k+"_FILE"] = true
+		names = append(names, k)
+		if collectionKind(opt) != reflect.Invalid {
+			collections = append(collections, k+"_")
 		}
-		k := strings.ToUpper(UnderscoreKey(prefixed))
+	}
 
-		v, ok := os.LookupEnv(k)
-		if !ok {
+	for _, opt := range l.opts {
+		k := e.name(opt.Key)
+		kind := collectionKind(opt)
+
+		v, loc, ok, err := e.lookup(k)
+		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
-		l.Set(key, v)
+
+		switch {
+		case len(opt.Fields) > 0:
+			list, loc, err := e.structList(k, opt, v, loc, ok)
+			if err != nil {
+				errs = append(errs, err)
+			} else if list != nil {
+				l.SetFrom(opt.Key, list, loc)
+			}
+
+		case kind == reflect.Slice:
+			if ok {
+				l.SetFrom(opt.Key, e.split(v), loc)
+			} else if list, loc := e.indexed(k); list != nil {
+				l.SetFrom(opt.Key, list, loc)
+			}
+
+		case kind == reflect.Map:
+			m, loc, err := e.mapValue(k, v, loc, ok, known)
+			if err != nil {
+				errs = append(errs, err)
+			} else if m != nil {
+				l.SetFrom(opt.Key, m, loc)
+			}
+
+		default:
+			if ok {
+				l.SetFrom(opt.Key, v, loc)
+			}
+		}
+	}
+
+	// In strict mode, look for variables which have the prefix
+	// but don't match an option, e.g. a misspelled variable.
+	// Without a prefix, there's no way to tell which variables
+	// are meant for this app.
+	if l.Strict && e.Prefix != "" {
+		prefix := strings.ToUpper(e.Prefix) + "_"
+
+		for _, kv := range e.Environ() {
+			k := strings.SplitN(kv, "=", 2)[0]
+			if !strings.HasPrefix(k, prefix) || known[k] || hasAnyPrefix(k, collections) {
+				continue
+			}
+			errs = append(errs, &UnknownKeyError{
+				Name:       k,
+				Location:   "environment",
+				Suggestion: suggest(k, names),
+			})
+		}
+	}
+
+	if errs != nil {
+		return combineErrors(errs)
 	}
 	return nil
 }
+
+// lookup returns the value of the variable "k", or else the contents
+// of the file named by "k_FILE", along with the name of the variable
+// the value came from.
+func (e *env) lookup(k string) (val, loc string, ok bool, err error) {
+	if v, ok := e.LookupEnv(k); ok {
+		return v, k, true, nil
+	}
+
+	path, ok := e.LookupEnv(k + "_FILE")
+	if !ok {
+		return "", "", false, nil
+	}
+	b, err := readFile(e.FS, path)
+	if err != nil {
+		return "", "", false, fmt.Errorf("reading %s: %v", k+"_FILE", err)
+	}
+	// Files usually end with a newline, which isn't part of the value.
+	v := strings.TrimRight(string(b), "\r\n")
+	return v, k + "_FILE", true, nil
+}
+
+// split splits a list value by the separator.
+func (e *env) split(v string) []string {
+	list := []string{}
+	if strings.TrimSpace(v) == "" {
+		return list
+	}
+	for _, item := range strings.Split(v, e.Separator) {
+		list = append(list, strings.TrimSpace(item))
+	}
+	return list
+}
+
+// indexed returns the values of indexed variables, e.g. APP_HOSTS_0,
+// APP_HOSTS_1, etc, stopping at the first missing index.
+// Returns nil if there are none.
+func (e *env) indexed(k string) ([]string, string) {
+	var list, locs []string
+	for i := 0; ; i++ {
+		name := k + "_" + strconv.Itoa(i)
+		v, ok := e.LookupEnv(name)
+		if !ok {
+			break
+		}
+		list = append(list, v)
+		locs = append(locs, name)
+	}
+	return list, strings.Join(locs, ", ")
+}
+
+// structList returns the value of a list of structs, from a variable
+// containing a JSON list, if "ok" is true, or else from indexed variables
+// for each field, e.g. APP_BACKENDS_0_ADDR and APP_BACKENDS_0_WEIGHT.
+// Returns nil if there are no values.
+func (e *env) structList(k string, opt *Opt, v, loc string, ok bool) ([]interface{}, string, error) {
+	if ok {
+		var list []interface{}
+		err := json.Unmarshal([]byte(v), &list)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: expected a JSON list: %v", loc, err)
+		}
+		return list, loc, nil
+	}
+
+	var list []interface{}
+	var locs []string
+	for i := 0; ; i++ {
+		elem := map[string]interface{}{}
+		for _, f := range opt.Fields {
+			name := e.name(elemKey(opt.Key, i, f.Key))
+			v, loc, ok, err := e.lookup(name)
+			if err != nil {
+				return nil, "", err
+			}
+			if ok {
+				setPath(elem, f.Key, v)
+				locs = append(locs, loc)
+			}
+		}
+		if len(elem) == 0 {
+			break
+		}
+		list = append(list, elem)
+	}
+	return list, strings.Join(locs, ", "), nil
+}
+
+// mapValue returns the value of a map option from a key=value list
+// variable, if "ok" is true, merged with any per-key variables,
+// e.g. APP_TAGS_ENV. Variables which belong to other options are skipped.
+// Returns nil if there are no values.
+func (e *env) mapValue(k, v, loc string, ok bool, known map[string]bool) (map[string]string, string, error) {
+	var m map[string]string
+	var locs []string
+
+	if ok {
+		m = map[string]string{}
+		locs = append(locs, loc)
+		for _, pair := range e.split(v) {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return nil, "", fmt.Errorf("%s: expected key=value, got %q", loc, pair)
+			}
+			m[kv[0]] = kv[1]
+		}
+	}
+
+	var keyed []string
+	for _, kv := range e.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		name := parts[0]
+		if !strings.HasPrefix(name, k+"_") || known[name] {
+			continue
+		}
+		keyed = append(keyed, name)
+		if m == nil {
+			m = map[string]string{}
+		}
+		m[strings.ToLower(strings.TrimPrefix(name, k+"_"))] = parts[1]
+	}
+	sort.Strings(keyed)
+	locs = append(locs, keyed...)
+
+	return m, strings.Join(locs, ", "), nil
+}
+
+// name returns the name of the environment variable for the given key.
+func (e *env) name(key []string) string {
+	return envName(e.Prefix, key)
+}
+
+// envName returns the name of the environment variable for the given
+// prefix and key, e.g. APP_SERVER_ADDR.
+func envName(prefix string, key []string) string {
+	var prefixed []string
+	if prefix != "" {
+		prefixed = append([]string{prefix}, key...)
+	} else {
+		prefixed = key
+	}
+	return strings.ToUpper(UnderscoreKey(prefixed))
+}
+
+func (e *env) String() string {
+	return "env"
+}
+
+// Static returns true: environment variables are not reloaded.
+func (e *env) Static() bool {
+	return true
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
 )
 
 func ExampleEnv() {
@@ -22,3 +27,163 @@ func ExampleEnv() {
 	// Output:
 	// baz
 }
+
+func ExampleEnv_strict() {
+	os.Setenv("STRICT_DB_PTH", "todo.db")
+
+	path := ""
+	opts := []*Opt{
+		{Key: []string{"db", "path"}, Value: &path},
+	}
+
+	l := NewLoader(opts, Env("strict"))
+	l.Strict = true
+	l.Load()
+	fmt.Println(l.Errors())
+	// Output:
+	// [unknown opt key "STRICT_DB_PTH" in environment, did you mean "STRICT_DB_PATH"?]
+}
+
+func ExampleEnvWith() {
+	os.Setenv("LISTS_HOSTS", "a.com; b.com")
+	os.Setenv("LISTS_PORTS_0", "80")
+	os.Setenv("LISTS_PORTS_1", "443")
+	os.Setenv("LISTS_TAGS", "env=prod;team=web")
+	os.Setenv("LISTS_TAGS_TEAM", "api")
+	defer func() {
+		for _, k := range []string{"LISTS_HOSTS", "LISTS_PORTS_0", "LISTS_PORTS_1", "LISTS_TAGS", "LISTS_TAGS_TEAM"} {
+			os.Unsetenv(k)
+		}
+	}()
+
+	var hosts []string
+	var ports []int
+	var tags map[string]string
+	opts := []*Opt{
+		{Key: []string{"hosts"}, Value: &hosts},
+		{Key: []string{"ports"}, Value: &ports},
+		{Key: []string{"tags"}, Value: &tags},
+	}
+
+	l := NewLoader(opts, EnvWith(EnvOpts{Prefix: "lists", Separator: ";"}))
+	l.Strict = true
+	l.Load()
+	fmt.Println(l.Errors())
+	fmt.Printf("%q\n", hosts)
+	fmt.Println(ports)
+	fmt.Println(tags)
+	fmt.Println(l.Source([]string{"ports"}))
+	// Output:
+	// []
+	// ["a.com" "b.com"]
+	// [80 443]
+	// map[env:prod team:api]
+	// ports = []string{"80", "443"} (env LISTS_PORTS_0, LISTS_PORTS_1)
+}
+
+func TestEnvFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cli-env-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "password")
+	err = ioutil.WriteFile(path, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("FILES_DB_PASSWORD_FILE", path)
+	defer os.Unsetenv("FILES_DB_PASSWORD_FILE")
+	os.Setenv("FILES_DB_USER_FILE", filepath.Join(dir, "missing"))
+	defer os.Unsetenv("FILES_DB_USER_FILE")
+
+	var password, user string
+	opts := []*Opt{
+		{Key: []string{"db", "password"}, Value: &password},
+		{Key: []string{"db", "user"}, Value: &user},
+	}
+	l := NewLoader(opts, Env("files"))
+	l.Strict = true
+	l.Load()
+
+	if password != "s3cret" {
+		t.Errorf("expected password from file, got %q", password)
+	}
+	if loc := l.Source([]string{"db", "password"}).Location; loc != "FILES_DB_PASSWORD_FILE" {
+		t.Errorf("unexpected location %q", loc)
+	}
+	if len(l.Errors()) != 1 {
+		t.Errorf("expected one error for the missing file, got %v", l.Errors())
+	}
+}
+
+func TestEnvLookup(t *testing.T) {
+	vars := map[string]string{
+		"LOOKUP_NAME":          "web",
+		"LOOKUP_TAGS_ENV":      "prod",
+		"LOOKUP_PASSWORD_FILE": "/run/secrets/password",
+		"LOOKUP_NAMR":          "typo",
+	}
+	fsys := fstest.MapFS{
+		"run/secrets/password": {Data: []byte("s3cret\n")},
+	}
+
+	var name, password string
+	var tags map[string]string
+	opts := []*Opt{
+		{Key: []string{"name"}, Value: &name},
+		{Key: []string{"password"}, Value: &password},
+		{Key: []string{"tags"}, Value: &tags},
+	}
+	l := NewLoader(opts, EnvWith(EnvOpts{
+		Prefix: "lookup",
+		LookupEnv: func(k string) (string, bool) {
+			v, ok := vars[k]
+			return v, ok
+		},
+		Environ: func() []string {
+			var env []string
+			for k, v := range vars {
+				env = append(env, k+"="+v)
+			}
+			return env
+		},
+		FS: fsys,
+	}))
+	l.Strict = true
+	l.Load()
+
+	if name != "web" || password != "s3cret" || tags["env"] != "prod" {
+		t.Errorf("unexpected values %q %q %v", name, password, tags)
+	}
+	errs := l.Errors()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "LOOKUP_NAMR") {
+		t.Errorf("expected an unknown key error for LOOKUP_NAMR, got %v", errs)
+	}
+}
+
+func ExampleEnv_structList() {
+	os.Setenv("LB_BACKENDS_0_ADDR", "a:80")
+	os.Setenv("LB_BACKENDS_0_MAXWEIGHT", "2")
+	os.Setenv("LB_BACKENDS_1_ADDR", "b:80")
+	os.Setenv("LB_BACKENDS_1_TLS_CERT", "b.pem")
+	defer func() {
+		for _, k := range []string{"LB_BACKENDS_0_ADDR", "LB_BACKENDS_0_MAXWEIGHT", "LB_BACKENDS_1_ADDR", "LB_BACKENDS_1_TLS_CERT"} {
+			os.Unsetenv(k)
+		}
+	}()
+
+	var backends []testBackend
+	opts := []*Opt{testBackendOpt(&backends)}
+
+	l := NewLoader(opts, Env("lb"))
+	l.Strict = true
+	l.Load()
+	fmt.Println(l.Errors())
+	fmt.Printf("%+v\n", backends)
+	// Output:
+	// []
+	// [{Addr:a:80 MaxWeight:2 TLS:{Cert:}} {Addr:b:80 MaxWeight:0 TLS:{Cert:b.pem}}]
+}
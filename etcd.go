@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EtcdOpts describes options related to loading options from etcd.
+type EtcdOpts struct {
+	// Addr is the address of an etcd server's HTTP/JSON gateway.
+	// Defaults to "http://127.0.0.1:2379".
+	Addr string
+	// Prefix is the key prefix to load, e.g. "/myapp/".
+	// A trailing "/" is added if missing, so that "/myapp" doesn't load
+	// sibling keys such as "/myapp2/addr". Keys under the prefix are mapped
+	// to option keys by splitting on "/", so "/myapp/server/addr" sets
+	// the "server.addr" option.
+	Prefix string
+	// Token is an auth token, from etcd's authenticate API,
+	// sent in the Authorization header.
+	Token string
+	// Timeout is the timeout of the request to etcd.
+	// Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// Etcd loads options from etcd, using the etcd v3 HTTP/JSON gateway,
+// so that a full etcd client isn't required.
+func Etcd(opts EtcdOpts) Provider {
+	return &etcd{opts}
+}
+
+type etcd struct {
+	opts EtcdOpts
+}
+
+func (e *etcd) String() string {
+	return "etcd"
+}
+
+// etcdRange is a request to etcd's range API. Keys are base64 encoded
+// in the JSON gateway, which encoding/json does for []byte.
+type etcdRange struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []struct {
+		Key   []byte `json:"key"`
+		Value []byte `json:"value"`
+	} `json:"kvs"`
+}
+
+func (e *etcd) Provide(l *Loader) error {
+	addr := e.opts.Addr
+	if addr == "" {
+		addr = "http://127.0.0.1:2379"
+	}
+
+	timeout := e.opts.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	prefix := e.opts.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	body, err := json.Marshal(etcdRange{
+		Key:      []byte(prefix),
+		RangeEnd: prefixEnd([]byte(prefix)),
+	})
+	if err != nil {
+		return fmt.Errorf("etcd: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", httpAddr(addr)+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("etcd: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.opts.Token != "" {
+		req.Header.Set("Authorization", e.opts.Token)
+	}
+
+	var resp etcdRangeResponse
+	err = requestJSON(req, timeout, &resp)
+	if err != nil {
+		return fmt.Errorf("etcd: %v", err)
+	}
+
+	for _, kv := range resp.Kvs {
+		key := splitPath(string(kv.Key), prefix)
+		if key == nil {
+			continue
+		}
+		l.SetFrom(key, string(kv.Value), string(kv.Key))
+	}
+	return nil
+}
+
+// prefixEnd returns the end of the range of keys which have the given prefix,
+// i.e. the prefix with the last byte incremented, as described by
+// etcd's range API.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// The prefix is all 0xff bytes (or empty), so range over all keys.
+	return []byte{0}
+}
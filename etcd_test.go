@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEtcd(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req etcdRange
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v3/kv/range" || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if string(req.Key) != "/myapp/" || string(req.RangeEnd) != "/myapp0" {
+			t.Errorf("unexpected range %q %q", req.Key, req.RangeEnd)
+		}
+		// Keys and values are base64 encoded:
+		// "/myapp/server/addr" = ":9090"
+		// "/myapp/server/workers" = "five"
+		w.Write([]byte(`{"kvs": [
+			{"key": "L215YXBwL3NlcnZlci9hZGRy", "value": "OjkwOTA="},
+			{"key": "L215YXBwL3NlcnZlci93b3JrZXJz", "value": "Zml2ZQ=="}
+		]}`))
+	}))
+	defer srv.Close()
+
+	var addr string
+	var workers int
+	opts := []*Opt{
+		{Key: []string{"Server", "Addr"}, Value: &addr},
+		{Key: []string{"Server", "Workers"}, Value: &workers},
+	}
+
+	l := NewLoader(opts, Etcd(EtcdOpts{
+		Addr:   srv.URL,
+		Prefix: "/myapp/",
+	}))
+	l.Load()
+
+	if addr != ":9090" {
+		t.Errorf("unexpected value %q", addr)
+	}
+
+	errs := l.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", errs)
+	}
+	expect := "setting server.workers from etcd /myapp/server/workers:"
+	if !strings.HasPrefix(errs[0].Error(), expect) {
+		t.Errorf("unexpected error: %s", errs[0])
+	}
+}
+
+func TestEtcdPrefix(t *testing.T) {
+	// etcd returns every key in the requested range.
+	keys := map[string]string{
+		"/app/addr":         ":9090",
+		"/app2/addr":        ":1",
+		"/application/addr": ":2",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req etcdRange
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		var resp etcdRangeResponse
+		for k, v := range keys {
+			if k >= string(req.Key) && k < string(req.RangeEnd) {
+				resp.Kvs = append(resp.Kvs, struct {
+					Key   []byte `json:"key"`
+					Value []byte `json:"value"`
+				}{[]byte(k), []byte(v)})
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	for _, prefix := range []string{"/app", "/app/"} {
+		var addr string
+		opts := []*Opt{
+			{Key: []string{"addr"}, Value: &addr},
+		}
+		l := NewLoader(opts, Etcd(EtcdOpts{Addr: srv.URL, Prefix: prefix}))
+		l.Load()
+
+		if errs := l.Errors(); errs != nil {
+			t.Fatal(prefix, errs)
+		}
+		if addr != ":9090" {
+			t.Errorf("prefix %q: unexpected addr %q", prefix, addr)
+		}
+	}
+}
@@ -1,476 +1,628 @@
 package main
 
 import cli "github.com/buchanae/cli"
+import context "context"
 import foo "github.com/buchanae/cli/examples/mailer/foo"
 
-var cmdSpecs = []cli.Spec{
-	&createMailboxSpec{
-		Opt: DefaultOpt(),
-	},
-	&deleteMailboxSpec{
-		Opt: DefaultOpt(),
-	},
-	&renameMailboxSpec{
-		Opt: DefaultOpt(),
-	},
-	&getMessageSpec{
-		Opt: DefaultOpt(),
-	},
-	&createMessageSpec{
-		Opt: DefaultOpt(),
-	},
-	&listMailboxesSpec{
-		Opt: DefaultOpt(),
-	},
-	&fooSpec{
-		Opt: foo.DefaultConfig(),
-	},
-	&noargSpec{},
+func specs() []cli.Spec {
+	return []cli.Spec{
+		&createMailboxSpec{
+			opt: DefaultOpt(),
+		},
+		&deleteMailboxSpec{
+			opt: DefaultOpt(),
+		},
+		&renameMailboxSpec{
+			opt: DefaultOpt(),
+		},
+		&getMessageSpec{
+			opt: DefaultOpt(),
+		},
+		&createMessageSpec{
+			opt: DefaultOpt(),
+		},
+		&listMailboxesSpec{
+			opt: DefaultOpt(),
+		},
+		&fooSpec{
+			opt: foo.DefaultConfig(),
+		},
+		&noargSpec{},
+	}
 }
 
 type createMailboxSpec struct {
-	opt Opt
+	cmd  *cli.Cmd
+	opt  Opt
 	args struct {
 		arg0 string
 	}
 }
 
-func (cmd *createMailboxSpec) Run() {
+func (cmd *createMailboxSpec) Run(ctx context.Context) (interface{}, error) {
 	CreateMailbox(
 		cmd.opt,
 		cmd.args.arg0,
 	)
+	return nil, nil
 }
 
-func (cmd *createMailboxCmd) Cmd() cli.Cmd {
-  return cli.Enrich(cli.Cmd{
-    Name: "CreateMailbox",
-    RawDoc: "Create a mailbox.\n\nCreate a new mailbox in the database.\n\nUsage: mailer create mailbox <mailbox name>\nExample: mailer create mailbox foobar\n",
-    Args: []cli.Arg{
-      {
-        Name:     "name",
-        Type:     "string",
-        Variadic: false,
-        Value:    &cmd.args.arg0,
-      },
-    },
-    Opts: []cli.Opt{
-      {
-        Key:   []string{"DB", "Path"},
-        Doc:   "Path to database directory\n",
-        Value: &cmd.Opt.DB.Path,
-      }, {
-        Key:   []string{"Foo", "Port"},
-        Doc:   "Server port to listen on.\n",
-        Value: &cmd.Opt.Foo.Port,
-      }, {
-        Key:   []string{"Foo", "Host"},
-        Doc:   "Server host to listen on.\n",
-        Value: &cmd.Opt.Foo.Host,
-      }, {
-        Key:   []string{"Foo", "User", "Username"},
-        Doc:   "User name for login.\n",
-        Value: &cmd.Opt.Foo.User.Username,
-      }, {
-        Key:   []string{"Foo", "User", "Password"},
-        Doc:   "Password for login.\n",
-        Value: &cmd.Opt.Foo.User.Password,
-      },
-    },
-  )
+func (cmd *createMailboxSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
-type deleteMailboxSpec struct {
-	Opt Opt
+func (cmd *createMailboxSpec) Cmd() *cli.Cmd {
+	if cmd.cmd != nil {
+		return cmd.cmd
+	}
+	cmd.cmd = &cli.Cmd{
+		RawName: "CreateMailbox",
+		RawDoc:  "Create a mailbox.\n\nCreate a new mailbox in the database.\n\nUsage: mailer create mailbox <mailbox name>\nExample: mailer create mailbox foobar\n",
+		Args: []*cli.Arg{
+			{
+				Name:     "name",
+				Type:     "string",
+				Variadic: false,
+				Value:    &cmd.args.arg0,
+			},
+		},
+		Opts: []*cli.Opt{
+			{
+				Key:          []string{"DB", "Path"},
+				RawDoc:       "Path to database directory\n",
+				Value:        &cmd.opt.DB.Path,
+				DefaultValue: cmd.opt.DB.Path,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Port"},
+				RawDoc:       "Server port to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Port,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Port,
+				Type:         "int",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Host"},
+				RawDoc:       "Server host to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Host,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Host,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Username"},
+				RawDoc:       "User name for login.\n",
+				Value:        &cmd.opt.Foo.User.Username,
+				DefaultValue: cmd.opt.Foo.User.Username,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Password"},
+				RawDoc:       "Password for login.\n",
+				Value:        &cmd.opt.Foo.User.Password,
+				DefaultValue: cmd.opt.Foo.User.Password,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			},
+		},
+	}
+	cli.Enrich(cmd.cmd)
+	return cmd.cmd
+}
 
+type deleteMailboxSpec struct {
+	cmd  *cli.Cmd
+	opt  Opt
 	args struct {
 		arg0 string
 	}
 }
 
-func (cmd *deleteMailboxSpec) Name() string {
-	return "DeleteMailbox"
-}
-
-func (cmd *deleteMailboxSpec) Doc() string {
-	return ""
-}
-
-func (cmd *deleteMailboxSpec) Run(args []string) {
-	cli.CheckArgs(args, cmd.ArgSpecs())
+func (cmd *deleteMailboxSpec) Run(ctx context.Context) (interface{}, error) {
 	DeleteMailbox(
-		cmd.Opt,
+		cmd.opt,
 		cmd.args.arg0,
 	)
+	return nil, nil
 }
 
-func (cmd *deleteMailboxSpec) ArgSpecs() []cli.ArgSpec {
-	return []cli.ArgSpec{
-		{
-			Name:     "name",
-			Type:     "string",
-			Variadic: false,
-			Value:    &cmd.args.arg0,
-		},
-	}
+func (cmd *deleteMailboxSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
-func (cmd *deleteMailboxSpec) OptSpecs() []cli.OptSpec {
-	return []cli.OptSpec{
-		{
-			Key:   []string{"DB", "Path"},
-			Doc:   "Path to database directory\n",
-			Value: &cmd.Opt.DB.Path,
-		}, {
-			Key:   []string{"Foo", "Port"},
-			Doc:   "Server port to listen on.\n",
-			Value: &cmd.Opt.Foo.Port,
-		}, {
-			Key:   []string{"Foo", "Host"},
-			Doc:   "Server host to listen on.\n",
-			Value: &cmd.Opt.Foo.Host,
-		}, {
-			Key:   []string{"Foo", "User", "Username"},
-			Doc:   "User name for login.\n",
-			Value: &cmd.Opt.Foo.User.Username,
-		}, {
-			Key:   []string{"Foo", "User", "Password"},
-			Doc:   "Password for login.\n",
-			Value: &cmd.Opt.Foo.User.Password,
+func (cmd *deleteMailboxSpec) Cmd() *cli.Cmd {
+	if cmd.cmd != nil {
+		return cmd.cmd
+	}
+	cmd.cmd = &cli.Cmd{
+		RawName: "DeleteMailbox",
+		RawDoc:  "",
+		Args: []*cli.Arg{
+			{
+				Name:     "name",
+				Type:     "string",
+				Variadic: false,
+				Value:    &cmd.args.arg0,
+			},
+		},
+		Opts: []*cli.Opt{
+			{
+				Key:          []string{"DB", "Path"},
+				RawDoc:       "Path to database directory\n",
+				Value:        &cmd.opt.DB.Path,
+				DefaultValue: cmd.opt.DB.Path,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Port"},
+				RawDoc:       "Server port to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Port,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Port,
+				Type:         "int",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Host"},
+				RawDoc:       "Server host to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Host,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Host,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Username"},
+				RawDoc:       "User name for login.\n",
+				Value:        &cmd.opt.Foo.User.Username,
+				DefaultValue: cmd.opt.Foo.User.Username,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Password"},
+				RawDoc:       "Password for login.\n",
+				Value:        &cmd.opt.Foo.User.Password,
+				DefaultValue: cmd.opt.Foo.User.Password,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
+	return cmd.cmd
 }
 
 type renameMailboxSpec struct {
-	Opt Opt
-
+	cmd  *cli.Cmd
+	opt  Opt
 	args struct {
 		arg0 string
-
 		arg1 string
 	}
 }
 
-func (cmd *renameMailboxSpec) Name() string {
-	return "RenameMailbox"
-}
-
-func (cmd *renameMailboxSpec) Doc() string {
-	return ""
-}
-
-func (cmd *renameMailboxSpec) Run(args []string) {
-	cli.CheckArgs(args, cmd.ArgSpecs())
+func (cmd *renameMailboxSpec) Run(ctx context.Context) (interface{}, error) {
 	RenameMailbox(
-		cmd.Opt,
+		cmd.opt,
 		cmd.args.arg0,
 		cmd.args.arg1,
 	)
+	return nil, nil
 }
 
-func (cmd *renameMailboxSpec) ArgSpecs() []cli.ArgSpec {
-	return []cli.ArgSpec{
-		{
-			Name:     "from",
-			Type:     "string",
-			Variadic: false,
-			Value:    &cmd.args.arg0,
-		}, {
-			Name:     "to",
-			Type:     "string",
-			Variadic: false,
-			Value:    &cmd.args.arg1,
-		},
-	}
+func (cmd *renameMailboxSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
-func (cmd *renameMailboxSpec) OptSpecs() []cli.OptSpec {
-	return []cli.OptSpec{
-		{
-			Key:   []string{"DB", "Path"},
-			Doc:   "Path to database directory\n",
-			Value: &cmd.Opt.DB.Path,
-		}, {
-			Key:   []string{"Foo", "Port"},
-			Doc:   "Server port to listen on.\n",
-			Value: &cmd.Opt.Foo.Port,
-		}, {
-			Key:   []string{"Foo", "Host"},
-			Doc:   "Server host to listen on.\n",
-			Value: &cmd.Opt.Foo.Host,
-		}, {
-			Key:   []string{"Foo", "User", "Username"},
-			Doc:   "User name for login.\n",
-			Value: &cmd.Opt.Foo.User.Username,
-		}, {
-			Key:   []string{"Foo", "User", "Password"},
-			Doc:   "Password for login.\n",
-			Value: &cmd.Opt.Foo.User.Password,
+func (cmd *renameMailboxSpec) Cmd() *cli.Cmd {
+	if cmd.cmd != nil {
+		return cmd.cmd
+	}
+	cmd.cmd = &cli.Cmd{
+		RawName: "RenameMailbox",
+		RawDoc:  "",
+		Args: []*cli.Arg{
+			{
+				Name:     "from",
+				Type:     "string",
+				Variadic: false,
+				Value:    &cmd.args.arg0,
+			}, {
+				Name:     "to",
+				Type:     "string",
+				Variadic: false,
+				Value:    &cmd.args.arg1,
+			},
+		},
+		Opts: []*cli.Opt{
+			{
+				Key:          []string{"DB", "Path"},
+				RawDoc:       "Path to database directory\n",
+				Value:        &cmd.opt.DB.Path,
+				DefaultValue: cmd.opt.DB.Path,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Port"},
+				RawDoc:       "Server port to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Port,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Port,
+				Type:         "int",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Host"},
+				RawDoc:       "Server host to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Host,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Host,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Username"},
+				RawDoc:       "User name for login.\n",
+				Value:        &cmd.opt.Foo.User.Username,
+				DefaultValue: cmd.opt.Foo.User.Username,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Password"},
+				RawDoc:       "Password for login.\n",
+				Value:        &cmd.opt.Foo.User.Password,
+				DefaultValue: cmd.opt.Foo.User.Password,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
+	return cmd.cmd
 }
 
 type getMessageSpec struct {
-	Opt Opt
-
+	cmd  *cli.Cmd
+	opt  Opt
 	args struct {
 		arg0 []int
 	}
 }
 
-func (cmd *getMessageSpec) Name() string {
-	return "GetMessage"
-}
-
-func (cmd *getMessageSpec) Doc() string {
-	return ""
-}
-
-func (cmd *getMessageSpec) Run(args []string) {
-	cli.CheckArgs(args, cmd.ArgSpecs())
+func (cmd *getMessageSpec) Run(ctx context.Context) (interface{}, error) {
 	GetMessage(
-		cmd.Opt,
+		cmd.opt,
 		cmd.args.arg0...,
 	)
+	return nil, nil
 }
 
-func (cmd *getMessageSpec) ArgSpecs() []cli.ArgSpec {
-	return []cli.ArgSpec{
-		{
-			Name:     "ids",
-			Type:     "[]int",
-			Variadic: true,
-			Value:    &cmd.args.arg0,
-		},
-	}
+func (cmd *getMessageSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
-func (cmd *getMessageSpec) OptSpecs() []cli.OptSpec {
-	return []cli.OptSpec{
-		{
-			Key:   []string{"DB", "Path"},
-			Doc:   "Path to database directory\n",
-			Value: &cmd.Opt.DB.Path,
-		}, {
-			Key:   []string{"Foo", "Port"},
-			Doc:   "Server port to listen on.\n",
-			Value: &cmd.Opt.Foo.Port,
-		}, {
-			Key:   []string{"Foo", "Host"},
-			Doc:   "Server host to listen on.\n",
-			Value: &cmd.Opt.Foo.Host,
-		}, {
-			Key:   []string{"Foo", "User", "Username"},
-			Doc:   "User name for login.\n",
-			Value: &cmd.Opt.Foo.User.Username,
-		}, {
-			Key:   []string{"Foo", "User", "Password"},
-			Doc:   "Password for login.\n",
-			Value: &cmd.Opt.Foo.User.Password,
+func (cmd *getMessageSpec) Cmd() *cli.Cmd {
+	if cmd.cmd != nil {
+		return cmd.cmd
+	}
+	cmd.cmd = &cli.Cmd{
+		RawName: "GetMessage",
+		RawDoc:  "",
+		Args: []*cli.Arg{
+			{
+				Name:     "ids",
+				Type:     "[]int",
+				Variadic: true,
+				Value:    &cmd.args.arg0,
+			},
+		},
+		Opts: []*cli.Opt{
+			{
+				Key:          []string{"DB", "Path"},
+				RawDoc:       "Path to database directory\n",
+				Value:        &cmd.opt.DB.Path,
+				DefaultValue: cmd.opt.DB.Path,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Port"},
+				RawDoc:       "Server port to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Port,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Port,
+				Type:         "int",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Host"},
+				RawDoc:       "Server host to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Host,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Host,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Username"},
+				RawDoc:       "User name for login.\n",
+				Value:        &cmd.opt.Foo.User.Username,
+				DefaultValue: cmd.opt.Foo.User.Username,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Password"},
+				RawDoc:       "Password for login.\n",
+				Value:        &cmd.opt.Foo.User.Password,
+				DefaultValue: cmd.opt.Foo.User.Password,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
+	return cmd.cmd
 }
 
 type createMessageSpec struct {
-	Opt Opt
-
+	cmd  *cli.Cmd
+	opt  Opt
 	args struct {
 		arg0 string
-
 		arg1 string
 	}
 }
 
-func (cmd *createMessageSpec) Name() string {
-	return "CreateMessage"
-}
-
-func (cmd *createMessageSpec) Doc() string {
-	return ""
-}
-
-func (cmd *createMessageSpec) Run(args []string) {
-	cli.CheckArgs(args, cmd.ArgSpecs())
+func (cmd *createMessageSpec) Run(ctx context.Context) (interface{}, error) {
 	CreateMessage(
-		cmd.Opt,
+		cmd.opt,
 		cmd.args.arg0,
 		cmd.args.arg1,
 	)
+	return nil, nil
 }
 
-func (cmd *createMessageSpec) ArgSpecs() []cli.ArgSpec {
-	return []cli.ArgSpec{
-		{
-			Name:     "mailbox",
-			Type:     "string",
-			Variadic: false,
-			Value:    &cmd.args.arg0,
-		}, {
-			Name:     "path",
-			Type:     "string",
-			Variadic: false,
-			Value:    &cmd.args.arg1,
-		},
-	}
+func (cmd *createMessageSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
-func (cmd *createMessageSpec) OptSpecs() []cli.OptSpec {
-	return []cli.OptSpec{
-		{
-			Key:   []string{"DB", "Path"},
-			Doc:   "Path to database directory\n",
-			Value: &cmd.Opt.DB.Path,
-		}, {
-			Key:   []string{"Foo", "Port"},
-			Doc:   "Server port to listen on.\n",
-			Value: &cmd.Opt.Foo.Port,
-		}, {
-			Key:   []string{"Foo", "Host"},
-			Doc:   "Server host to listen on.\n",
-			Value: &cmd.Opt.Foo.Host,
-		}, {
-			Key:   []string{"Foo", "User", "Username"},
-			Doc:   "User name for login.\n",
-			Value: &cmd.Opt.Foo.User.Username,
-		}, {
-			Key:   []string{"Foo", "User", "Password"},
-			Doc:   "Password for login.\n",
-			Value: &cmd.Opt.Foo.User.Password,
+func (cmd *createMessageSpec) Cmd() *cli.Cmd {
+	if cmd.cmd != nil {
+		return cmd.cmd
+	}
+	cmd.cmd = &cli.Cmd{
+		RawName: "CreateMessage",
+		RawDoc:  "",
+		Args: []*cli.Arg{
+			{
+				Name:     "mailbox",
+				Type:     "string",
+				Variadic: false,
+				Value:    &cmd.args.arg0,
+			}, {
+				Name:     "path",
+				Type:     "string",
+				Variadic: false,
+				Value:    &cmd.args.arg1,
+			},
+		},
+		Opts: []*cli.Opt{
+			{
+				Key:          []string{"DB", "Path"},
+				RawDoc:       "Path to database directory\n",
+				Value:        &cmd.opt.DB.Path,
+				DefaultValue: cmd.opt.DB.Path,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Port"},
+				RawDoc:       "Server port to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Port,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Port,
+				Type:         "int",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Host"},
+				RawDoc:       "Server host to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Host,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Host,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Username"},
+				RawDoc:       "User name for login.\n",
+				Value:        &cmd.opt.Foo.User.Username,
+				DefaultValue: cmd.opt.Foo.User.Username,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Password"},
+				RawDoc:       "Password for login.\n",
+				Value:        &cmd.opt.Foo.User.Password,
+				DefaultValue: cmd.opt.Foo.User.Password,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
+	return cmd.cmd
 }
 
 type listMailboxesSpec struct {
-	Opt Opt
-
+	cmd  *cli.Cmd
+	opt  Opt
 	args struct {
 	}
 }
 
-func (cmd *listMailboxesSpec) Name() string {
-	return "ListMailboxes"
-}
-
-func (cmd *listMailboxesSpec) Doc() string {
-	return ""
-}
-
-func (cmd *listMailboxesSpec) Run(args []string) {
-	cli.CheckArgs(args, cmd.ArgSpecs())
+func (cmd *listMailboxesSpec) Run(ctx context.Context) (interface{}, error) {
 	ListMailboxes(
-		cmd.Opt,
+		cmd.opt,
 	)
+	return nil, nil
+}
+
+func (cmd *listMailboxesSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
-func (cmd *listMailboxesSpec) ArgSpecs() []cli.ArgSpec {
-
-	return nil
-
-}
-
-func (cmd *listMailboxesSpec) OptSpecs() []cli.OptSpec {
-	return []cli.OptSpec{
-		{
-			Key:   []string{"DB", "Path"},
-			Doc:   "Path to database directory\n",
-			Value: &cmd.Opt.DB.Path,
-		}, {
-			Key:   []string{"Foo", "Port"},
-			Doc:   "Server port to listen on.\n",
-			Value: &cmd.Opt.Foo.Port,
-		}, {
-			Key:   []string{"Foo", "Host"},
-			Doc:   "Server host to listen on.\n",
-			Value: &cmd.Opt.Foo.Host,
-		}, {
-			Key:   []string{"Foo", "User", "Username"},
-			Doc:   "User name for login.\n",
-			Value: &cmd.Opt.Foo.User.Username,
-		}, {
-			Key:   []string{"Foo", "User", "Password"},
-			Doc:   "Password for login.\n",
-			Value: &cmd.Opt.Foo.User.Password,
+func (cmd *listMailboxesSpec) Cmd() *cli.Cmd {
+	if cmd.cmd != nil {
+		return cmd.cmd
+	}
+	cmd.cmd = &cli.Cmd{
+		RawName: "ListMailboxes",
+		RawDoc:  "",
+		Args:    []*cli.Arg{},
+		Opts: []*cli.Opt{
+			{
+				Key:          []string{"DB", "Path"},
+				RawDoc:       "Path to database directory\n",
+				Value:        &cmd.opt.DB.Path,
+				DefaultValue: cmd.opt.DB.Path,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Port"},
+				RawDoc:       "Server port to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Port,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Port,
+				Type:         "int",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "Host"},
+				RawDoc:       "Server host to listen on.\n",
+				Value:        &cmd.opt.Foo.ServerConfig.Host,
+				DefaultValue: cmd.opt.Foo.ServerConfig.Host,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Username"},
+				RawDoc:       "User name for login.\n",
+				Value:        &cmd.opt.Foo.User.Username,
+				DefaultValue: cmd.opt.Foo.User.Username,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Foo", "User", "Password"},
+				RawDoc:       "Password for login.\n",
+				Value:        &cmd.opt.Foo.User.Password,
+				DefaultValue: cmd.opt.Foo.User.Password,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
+	return cmd.cmd
 }
 
 type fooSpec struct {
-	Opt foo.Config
-
+	cmd  *cli.Cmd
+	opt  foo.Config
 	args struct {
 	}
 }
 
-func (cmd *fooSpec) Name() string {
-	return "Foo"
-}
-
-func (cmd *fooSpec) Doc() string {
-	return ""
-}
-
-func (cmd *fooSpec) Run(args []string) {
-	cli.CheckArgs(args, cmd.ArgSpecs())
+func (cmd *fooSpec) Run(ctx context.Context) (interface{}, error) {
 	Foo(
-		cmd.Opt,
+		cmd.opt,
 	)
+	return nil, nil
 }
 
-func (cmd *fooSpec) ArgSpecs() []cli.ArgSpec {
-
-	return nil
-
+func (cmd *fooSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
-func (cmd *fooSpec) OptSpecs() []cli.OptSpec {
-	return []cli.OptSpec{
-		{
-			Key:   []string{"Port"},
-			Doc:   "Server port to listen on.\n",
-			Value: &cmd.Opt.Port,
-		}, {
-			Key:   []string{"Host"},
-			Doc:   "Server host to listen on.\n",
-			Value: &cmd.Opt.Host,
-		}, {
-			Key:   []string{"User", "Username"},
-			Doc:   "User name for login.\n",
-			Value: &cmd.Opt.User.Username,
-		}, {
-			Key:   []string{"User", "Password"},
-			Doc:   "Password for login.\n",
-			Value: &cmd.Opt.User.Password,
+func (cmd *fooSpec) Cmd() *cli.Cmd {
+	if cmd.cmd != nil {
+		return cmd.cmd
+	}
+	cmd.cmd = &cli.Cmd{
+		RawName: "Foo",
+		RawDoc:  "",
+		Args:    []*cli.Arg{},
+		Opts: []*cli.Opt{
+			{
+				Key:          []string{"Port"},
+				RawDoc:       "Server port to listen on.\n",
+				Value:        &cmd.opt.ServerConfig.Port,
+				DefaultValue: cmd.opt.ServerConfig.Port,
+				Type:         "int",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"Host"},
+				RawDoc:       "Server host to listen on.\n",
+				Value:        &cmd.opt.ServerConfig.Host,
+				DefaultValue: cmd.opt.ServerConfig.Host,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"User", "Username"},
+				RawDoc:       "User name for login.\n",
+				Value:        &cmd.opt.User.Username,
+				DefaultValue: cmd.opt.User.Username,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			}, {
+				Key:          []string{"User", "Password"},
+				RawDoc:       "Password for login.\n",
+				Value:        &cmd.opt.User.Password,
+				DefaultValue: cmd.opt.User.Password,
+				Type:         "string",
+				Short:        "",
+				Tag:          "",
+			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
+	return cmd.cmd
 }
 
 type noargSpec struct {
+	cmd *cli.Cmd
+
 	args struct {
 	}
 }
 
-func (cmd *noargSpec) Name() string {
-	return "Noarg"
-}
-
-func (cmd *noargSpec) Doc() string {
-	return ""
-}
-
-func (cmd *noargSpec) Run(args []string) {
-	cli.CheckArgs(args, cmd.ArgSpecs())
+func (cmd *noargSpec) Run(ctx context.Context) (interface{}, error) {
 	Noarg()
+	return nil, nil
 }
 
-func (cmd *noargSpec) ArgSpecs() []cli.ArgSpec {
-
-	return nil
-
-}
-
-func (cmd *noargSpec) OptSpecs() []cli.OptSpec {
-
-	return nil
-
+func (cmd *noargSpec) Cmd() *cli.Cmd {
+	if cmd.cmd != nil {
+		return cmd.cmd
+	}
+	cmd.cmd = &cli.Cmd{
+		RawName: "Noarg",
+		RawDoc:  "",
+		Args:    []*cli.Arg{},
+		Opts:    []*cli.Opt{},
+	}
+	cli.Enrich(cmd.cmd)
+	return cmd.cmd
 }
 
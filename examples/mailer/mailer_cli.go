@@ -21,7 +21,7 @@ func main() {
 		b.AddSpec(spec, p)
 	}
 
-	b.Execute()
+	cli.Exit(b.Execute())
 }
 
 func initDB(opt Opt) *model.DB {
@@ -1,40 +1,16 @@
 package main
 
 import cli "github.com/buchanae/cli"
+import context "context"
 
 func specs() []cli.Spec {
 	return []cli.Spec{
-		&defaultServerOptSpec{},
 		&runSpec{
 			opt: DefaultServerOpt(),
 		},
 	}
 }
 
-type defaultServerOptSpec struct {
-	cmd *cli.Cmd
-
-	args struct {
-	}
-}
-
-func (cmd *defaultServerOptSpec) Run() {
-	DefaultServerOpt()
-}
-
-func (cmd *defaultServerOptSpec) Cmd() *cli.Cmd {
-	if cmd.cmd != nil {
-		return cmd.cmd
-	}
-	cmd.cmd = &cli.Cmd{
-		FuncName: "DefaultServerOpt",
-		RawDoc:   "",
-		Args:     []*cli.Arg{},
-		Opts:     []*cli.Opt{},
-	}
-	return cmd.cmd
-}
-
 type runSpec struct {
 	cmd  *cli.Cmd
 	opt  ServerOpt
@@ -43,11 +19,16 @@ type runSpec struct {
 	}
 }
 
-func (cmd *runSpec) Run() {
-	Run(
+func (cmd *runSpec) Run(ctx context.Context) (interface{}, error) {
+	Run(ctx,
 		cmd.opt,
 		cmd.args.arg0,
 	)
+	return nil, nil
+}
+
+func (cmd *runSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
 func (cmd *runSpec) Cmd() *cli.Cmd {
@@ -55,8 +36,9 @@ func (cmd *runSpec) Cmd() *cli.Cmd {
 		return cmd.cmd
 	}
 	cmd.cmd = &cli.Cmd{
-		FuncName: "Run",
-		RawDoc:   "",
+		RawName:    "Run",
+		RawDoc:     "",
+		HasContext: true,
 		Args: []*cli.Arg{
 			{
 				Name:     "msg",
@@ -73,6 +55,7 @@ func (cmd *runSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Name,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"Addr"},
 				RawDoc:       "Address to listen on.\n",
@@ -80,8 +63,11 @@ func (cmd *runSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Addr,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
 	return cmd.cmd
 }
+
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/buchanae/cli"
 	"net/http"
@@ -23,13 +24,24 @@ func DefaultServerOpt() ServerOpt {
 	}
 }
 
-func Run(opt ServerOpt, msg string) {
+func Run(ctx context.Context, opt ServerOpt, msg string) {
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "from server %q: %s\n", opt.Name, msg)
 	})
-	cli.Check(http.ListenAndServe(opt.Addr, nil))
+	srv := &http.Server{Addr: opt.Addr}
+
+	// Shut down gracefully on SIGINT/SIGTERM.
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	err := srv.ListenAndServe()
+	if err != http.ErrServerClosed {
+		cli.Check(err)
+	}
 }
 
 func main() {
-	cli.AutoCobra("hello-world", specs())
+	cli.Exit(cli.AutoCobra("hello-world", specs()))
 }
@@ -1,6 +1,7 @@
 package main
 
 import cli "github.com/buchanae/cli"
+import context "context"
 import time "time"
 
 func specs() []cli.Spec {
@@ -31,11 +32,16 @@ type addSpec struct {
 	}
 }
 
-func (cmd *addSpec) Run() {
+func (cmd *addSpec) Run(ctx context.Context) (interface{}, error) {
 	Add(
 		cmd.opt,
 		cmd.args.arg0,
 	)
+	return nil, nil
+}
+
+func (cmd *addSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
 func (cmd *addSpec) Cmd() *cli.Cmd {
@@ -43,8 +49,8 @@ func (cmd *addSpec) Cmd() *cli.Cmd {
 		return cmd.cmd
 	}
 	cmd.cmd = &cli.Cmd{
-		FuncName: "Add",
-		RawDoc:   "Add a new todo item.\nExample: todo add --snooze 5d \"get a life!\"\n",
+		RawName: "Add",
+		RawDoc:  "Add a new todo item.\nExample: todo add --snooze 5d \"get a life!\"\n",
 		Args: []*cli.Arg{
 			{
 				Name:     "description",
@@ -57,24 +63,27 @@ func (cmd *addSpec) Cmd() *cli.Cmd {
 			{
 				Key:          []string{"Config"},
 				RawDoc:       "Path to config file.\n",
-				Value:        &cmd.opt.Config,
-				DefaultValue: cmd.opt.Config,
+				Value:        &cmd.opt.Opt.Config,
+				DefaultValue: cmd.opt.Opt.Config,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"DB", "Path"},
 				RawDoc:       "",
-				Value:        &cmd.opt.DB.Path,
-				DefaultValue: cmd.opt.DB.Path,
+				Value:        &cmd.opt.Opt.DB.Path,
+				DefaultValue: cmd.opt.Opt.DB.Path,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"Stdout"},
 				RawDoc:       "",
-				Value:        &cmd.opt.Stdout,
-				DefaultValue: cmd.opt.Stdout,
+				Value:        &cmd.opt.Opt.Stdout,
+				DefaultValue: cmd.opt.Opt.Stdout,
 				Type:         "io.Writer",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"Snooze"},
 				RawDoc:       "",
@@ -82,6 +91,7 @@ func (cmd *addSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Snooze,
 				Type:         "time.Duration",
 				Short:        "s",
+				Tag:          "short:\"s\"",
 			}, {
 				Key:          []string{"Tags"},
 				RawDoc:       "",
@@ -89,9 +99,11 @@ func (cmd *addSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Tags,
 				Type:         "map[string]string",
 				Short:        "",
+				Tag:          "",
 			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
 	return cmd.cmd
 }
 
@@ -103,20 +115,24 @@ type deleteSpec struct {
 	}
 }
 
-func (cmd *deleteSpec) Run() {
-	Delete(
+func (cmd *deleteSpec) Run(ctx context.Context) (interface{}, error) {
+	return nil, Delete(
 		cmd.opt,
 		cmd.args.arg0...,
 	)
 }
 
+func (cmd *deleteSpec) Opt() interface{} {
+	return &cmd.opt
+}
+
 func (cmd *deleteSpec) Cmd() *cli.Cmd {
 	if cmd.cmd != nil {
 		return cmd.cmd
 	}
 	cmd.cmd = &cli.Cmd{
-		FuncName: "Delete",
-		RawDoc:   "Delete todo items.\nAliases: del\nExample: todo delete 1 2\n",
+		RawName: "Delete",
+		RawDoc:  "Delete todo items.\nAliases: del\nExample: todo delete 1 2\n",
 		Args: []*cli.Arg{
 			{
 				Name:     "ids",
@@ -133,6 +149,7 @@ func (cmd *deleteSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Config,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"DB", "Path"},
 				RawDoc:       "",
@@ -140,6 +157,7 @@ func (cmd *deleteSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.DB.Path,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"Stdout"},
 				RawDoc:       "",
@@ -147,9 +165,11 @@ func (cmd *deleteSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Stdout,
 				Type:         "io.Writer",
 				Short:        "",
+				Tag:          "",
 			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
 	return cmd.cmd
 }
 
@@ -160,10 +180,15 @@ type listSpec struct {
 	}
 }
 
-func (cmd *listSpec) Run() {
+func (cmd *listSpec) Run(ctx context.Context) (interface{}, error) {
 	List(
 		cmd.opt,
 	)
+	return nil, nil
+}
+
+func (cmd *listSpec) Opt() interface{} {
+	return &cmd.opt
 }
 
 func (cmd *listSpec) Cmd() *cli.Cmd {
@@ -171,9 +196,9 @@ func (cmd *listSpec) Cmd() *cli.Cmd {
 		return cmd.cmd
 	}
 	cmd.cmd = &cli.Cmd{
-		FuncName: "List",
-		RawDoc:   "List all todo items.\n",
-		Args:     []*cli.Arg{},
+		RawName: "List",
+		RawDoc:  "List all todo items.\n",
+		Args:    []*cli.Arg{},
 		Opts: []*cli.Opt{
 			{
 				Key:          []string{"Config"},
@@ -182,6 +207,7 @@ func (cmd *listSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Config,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"DB", "Path"},
 				RawDoc:       "",
@@ -189,6 +215,7 @@ func (cmd *listSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.DB.Path,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"Stdout"},
 				RawDoc:       "",
@@ -196,9 +223,11 @@ func (cmd *listSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Stdout,
 				Type:         "io.Writer",
 				Short:        "",
+				Tag:          "",
 			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
 	return cmd.cmd
 }
 
@@ -211,21 +240,25 @@ type snoozeSpec struct {
 	}
 }
 
-func (cmd *snoozeSpec) Run() {
-	Snooze(
+func (cmd *snoozeSpec) Run(ctx context.Context) (interface{}, error) {
+	return nil, Snooze(
 		cmd.opt,
 		cmd.args.arg0,
 		cmd.args.arg1,
 	)
 }
 
+func (cmd *snoozeSpec) Opt() interface{} {
+	return &cmd.opt
+}
+
 func (cmd *snoozeSpec) Cmd() *cli.Cmd {
 	if cmd.cmd != nil {
 		return cmd.cmd
 	}
 	cmd.cmd = &cli.Cmd{
-		FuncName: "Snooze",
-		RawDoc:   "Snooze a todo item.\nAliases: snz\nExample: todo snooze 1 3h\n",
+		RawName: "Snooze",
+		RawDoc:  "Snooze a todo item.\nAliases: snz\nExample: todo snooze 1 3h\n",
 		Args: []*cli.Arg{
 			{
 				Name:     "id",
@@ -247,6 +280,7 @@ func (cmd *snoozeSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Config,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"DB", "Path"},
 				RawDoc:       "",
@@ -254,6 +288,7 @@ func (cmd *snoozeSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.DB.Path,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"Stdout"},
 				RawDoc:       "",
@@ -261,9 +296,11 @@ func (cmd *snoozeSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Stdout,
 				Type:         "io.Writer",
 				Short:        "",
+				Tag:          "",
 			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
 	return cmd.cmd
 }
 
@@ -275,20 +312,24 @@ type removeSpec struct {
 	}
 }
 
-func (cmd *removeSpec) Run() {
-	Remove(
+func (cmd *removeSpec) Run(ctx context.Context) (interface{}, error) {
+	return nil, Remove(
 		cmd.opt,
 		cmd.args.arg0,
 	)
 }
 
+func (cmd *removeSpec) Opt() interface{} {
+	return &cmd.opt
+}
+
 func (cmd *removeSpec) Cmd() *cli.Cmd {
 	if cmd.cmd != nil {
 		return cmd.cmd
 	}
 	cmd.cmd = &cli.Cmd{
-		FuncName: "Remove",
-		RawDoc:   "Remove a todo item.\nDeprecated: please use \"delete\".\nHidden\nExample: todo remove 1\n",
+		RawName: "Remove",
+		RawDoc:  "Remove a todo item.\nDeprecated: please use \"delete\".\nHidden\nExample: todo remove 1\n",
 		Args: []*cli.Arg{
 			{
 				Name:     "id",
@@ -305,6 +346,7 @@ func (cmd *removeSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Config,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"DB", "Path"},
 				RawDoc:       "",
@@ -312,6 +354,7 @@ func (cmd *removeSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.DB.Path,
 				Type:         "string",
 				Short:        "",
+				Tag:          "",
 			}, {
 				Key:          []string{"Stdout"},
 				RawDoc:       "",
@@ -319,8 +362,11 @@ func (cmd *removeSpec) Cmd() *cli.Cmd {
 				DefaultValue: cmd.opt.Stdout,
 				Type:         "io.Writer",
 				Short:        "",
+				Tag:          "",
 			},
 		},
 	}
+	cli.Enrich(cmd.cmd)
 	return cmd.cmd
 }
+
@@ -21,14 +21,14 @@ func main() {
 		flags := cli.PFlags(cmd.Flags(), opts, cli.DotKey)
 
 		l := cli.NewLoader(opts,
-			cli.Env("TODO"),
 			flags,
-			cli.YAML(cli.DefaultYAML),
+			cli.Env("TODO"),
+			cli.Layered(cli.DefaultLayers("todo")),
 		)
 		b.SetRunner(cmd, spec, l)
 	}
 
-	b.Execute()
+	cli.Exit(b.Execute())
 }
 
 // Add a new todo item.
@@ -44,12 +44,15 @@ func Add(opt AddOpt, description string) {
 // Delete todo items.
 // Aliases: del
 // Example: todo delete 1 2
-func Delete(opt Opt, ids ...int) {
+func Delete(opt Opt, ids ...int) error {
 	db := openDB(opt)
 	for _, id := range ids {
 		err := db.Delete(id)
-		cli.Check(err)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // List all todo items.
@@ -76,21 +79,22 @@ func List(opt Opt) {
 // Snooze a todo item.
 // Aliases: snz
 // Example: todo snooze 1 3h
-func Snooze(opt Opt, id int, dur time.Duration) {
+func Snooze(opt Opt, id int, dur time.Duration) error {
 	db := openDB(opt)
 	todo, err := db.Get(id)
-	cli.Check(err)
+	if err != nil {
+		return err
+	}
 	todo.Due = todo.Due.Add(dur)
-	err = db.Update(todo)
-	cli.Check(err)
+	return db.Update(todo)
 }
 
 // Remove a todo item.
 // Deprecated: please use "delete".
 // Hidden
 // Example: todo remove 1
-func Remove(opt Opt, id int) {
-	Delete(opt, id)
+func Remove(opt Opt, id int) error {
+	return Delete(opt, id)
 }
 
 func openDB(opt Opt) *db.DB {
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+	"os"
+)
+
+// Exit codes returned by ExitCode.
+const (
+	// ExitFatal is the exit code of ErrFatal,
+	// and of errors which don't choose their own code.
+	ExitFatal = 1
+	// ExitUsage is the exit code of ErrUsage, e.g. an unknown flag.
+	ExitUsage = 2
+	// ExitConfig is the exit code of errors loading or validating
+	// option values, e.g. an invalid config file. This is EX_CONFIG
+	// from sysexits.h.
+	ExitConfig = 78
+)
+
+// ExitCoder is implemented by errors which choose their own exit code.
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// ErrConfig is used to signal errors loading option values,
+// i.e. the errors from Loader.Errors.
+type ErrConfig struct {
+	error
+}
+
+// ExitCode returns ExitConfig.
+func (ErrConfig) ExitCode() int {
+	return ExitConfig
+}
+
+// ExitCode returns the exit code for an error: 0 for nil, the code chosen
+// by errors which implement ExitCoder (which includes ErrUsage, ErrFatal,
+// ErrConfig, and ValidationErrors), including wrapped errors, e.g.
+// fmt.Errorf("...: %w", err), or else ExitFatal.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var c ExitCoder
+	if errors.As(err, &c) {
+		return c.ExitCode()
+	}
+	return ExitFatal
+}
+
+// exit is replaced by tests.
+var exit = os.Exit
+
+// Exit exits the process with the exit code for the error, see ExitCode.
+// The error isn't printed, since cobra prints it, so this is normally used
+// with the result of AutoCobra or Cobra.Execute, for example:
+//
+//   func main() {
+//     cli.Exit(cli.AutoCobra("app", specs()))
+//   }
+func Exit(err error) {
+	exit(ExitCode(err))
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+type testExitErr struct{}
+
+func (testExitErr) Error() string { return "custom" }
+func (testExitErr) ExitCode() int { return 42 }
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, 0},
+		{errors.New("failed"), ExitFatal},
+		{ErrFatal{errors.New("failed")}, ExitFatal},
+		{ErrUsage{errors.New("bad flag")}, ExitUsage},
+		{ErrConfig{errors.New("bad config")}, ExitConfig},
+		{ValidationErrors{{Err: errors.New("too big")}}, ExitConfig},
+		{testExitErr{}, 42},
+		{fmt.Errorf("wrapped: %w", testExitErr{}), 42},
+		{fmt.Errorf("wrapped: %w", ErrUsage{errors.New("bad flag")}), ExitUsage},
+		{ErrFatal{testExitErr{}}, 42},
+		{ErrFatal{fmt.Errorf("wrapped: %w", testExitErr{})}, 42},
+	}
+	for _, test := range tests {
+		if code := ExitCode(test.err); code != test.code {
+			t.Errorf("expected exit code %d for %v, got %d", test.code, test.err, code)
+		}
+	}
+
+	var code int
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+	Exit(testExitErr{})
+	if code != 42 {
+		t.Errorf("expected Exit to exit with 42, got %d", code)
+	}
+}
+
+func TestCobraExitCodes(t *testing.T) {
+	os.Setenv("EXIT_PORT", "not-a-port")
+	defer os.Unsetenv("EXIT_PORT")
+
+	spec := &testSpec{}
+	b := Cobra{}
+	b.Use = "app"
+	b.SilenceUsage = true
+	cmd := b.Add(spec)
+	opts := spec.Cmd().Opts
+	b.SetRunner(cmd, spec, NewLoader(opts, PFlags(cmd.Flags(), opts, DotKey), Env("exit")))
+
+	tests := []struct {
+		args  []string
+		code  int
+		usage bool
+	}{
+		{[]string{"server", "run", "--nope"}, ExitUsage, true},
+		{[]string{"server", "run", "extra"}, ExitUsage, true},
+		{[]string{"nope"}, ExitUsage, false},
+		{[]string{"server", "run"}, ExitConfig, false},
+	}
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		b.SetOutput(out)
+		b.SetArgs(test.args)
+
+		err := b.Execute()
+		if code := ExitCode(err); code != test.code {
+			t.Errorf("%v: expected exit code %d, got %d: %v", test.args, test.code, code, err)
+		}
+		if usage := strings.Contains(out.String(), "Usage:"); usage != test.usage {
+			t.Errorf("%v: expected usage to be printed: %v, got:\n%s", test.args, test.usage, out)
+		}
+	}
+}
+
+// testCheckSpec is a spec whose command function calls Check.
+type testCheckSpec struct {
+	testListSpec
+}
+
+func (t *testCheckSpec) Run(ctx context.Context) (interface{}, error) {
+	Check(t.err)
+	return nil, nil
+}
+
+func TestCheckExitCode(t *testing.T) {
+	spec := &testCheckSpec{testListSpec{err: testExitErr{}}}
+	_, err := RunContext(context.Background(), spec, NewLoader(nil), nil)
+	if _, ok := err.(ErrFatal); !ok {
+		t.Fatalf("expected ErrFatal, got %#v", err)
+	}
+	if code := ExitCode(err); code != 42 {
+		t.Errorf("expected Check to keep the exit code 42, got %d", code)
+	}
+}
@@ -2,10 +2,14 @@ package cli
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/BurntSushi/toml"
 	"github.com/ghodss/yaml"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // DefaultYAML contains the most common configuration
@@ -33,8 +37,11 @@ var DefaultTOML = FileOpts{
 // options from a file.
 type FileOpts struct {
 	// Paths is a list of paths to look for a config file.
-	// Environment variables will be expanded using `os.ExpandEnv`.
-	// Loading will stop on the first path that exists.
+	// Environment variables will be expanded, e.g. "$HOME/config.yaml",
+	// and $XDG_CONFIG_HOME defaults to $HOME/.config.
+	// All paths which exist are loaded. For YAML, JSON, and TOML,
+	// values from earlier paths take priority. For Layered,
+	// values from later paths take priority.
 	Paths []string
 	// OptKey is used to look for a config file path set by
 	// an option (e.g. by a flag or env. var). For example,
@@ -42,60 +49,258 @@ type FileOpts struct {
 	// file from a "--config.file" flag. OptKey is prepended
 	// to the Paths list, so it takes priority.
 	OptKey []string
+	// FS is the file system to read config files from. Defaults to the
+	// OS file system. Paths are converted to FS paths by removing a leading
+	// "/", e.g. "/etc/app/config.yaml" is "etc/app/config.yaml" in FS.
+	//
+	// FS may be an embed.FS, to ship default config files in the binary.
+	// Since all paths are read from FS, use a separate provider with the
+	// lowest precedence for the defaults, for example:
+	//
+	//   //go:embed defaults.yaml
+	//   var defaults embed.FS
+	//
+	//   cli.NewLoader(opts,
+	//     cli.Layered(cli.DefaultLayers("app")),
+	//     cli.YAML(cli.FileOpts{Paths: []string{"defaults.yaml"}, FS: defaults}),
+	//   )
+	FS fs.FS
+	// LookupEnv looks up the environment variables expanded in Paths.
+	// Defaults to os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
 }
 
 // YAML loads options from a YAML file.
 func YAML(opts FileOpts) Provider {
-	return &fileProvider{opts, unmarshalYAML}
+	return &fileProvider{fileDefaults(opts), unmarshalYAML, "yaml"}
 }
 
 // JSON loads options from a JSON file.
 func JSON(opts FileOpts) Provider {
-	return &fileProvider{opts, json.Unmarshal}
+	return &fileProvider{fileDefaults(opts), json.Unmarshal, "json"}
 }
 
 // TOML loads options from a TOML file.
 func TOML(opts FileOpts) Provider {
-	return &fileProvider{opts, toml.Unmarshal}
+	return &fileProvider{fileDefaults(opts), toml.Unmarshal, "toml"}
+}
+
+// fileDefaults sets the defaults of unset fields of FileOpts.
+func fileDefaults(opts FileOpts) FileOpts {
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	return opts
 }
 
 type fileProvider struct {
 	opts FileOpts
 	unm  unmarshaler
+	name string
 }
 
-func (f *fileProvider) Provide(l *Loader) error {
-	optKey := f.opts.OptKey
-	paths := f.opts.Paths
-	opt := l.GetString(optKey)
-	paths = append([]string{opt}, paths...)
+func (f *fileProvider) String() string {
+	return f.name
+}
 
-	for _, path := range paths {
-		path := os.ExpandEnv(path)
-		if path == "" || !exists(path) {
+func (f *fileProvider) Provide(l *Loader) error {
+	for _, path := range f.files(l) {
+		if !exists(f.opts.FS, path) {
 			continue
 		}
 
-		b, err := ioutil.ReadFile(path)
+		err := loadFile(l, f.opts.FS, path, f.unm)
 		if err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
+
+func (f *fileProvider) files(l *Loader) []string {
+	return expandPaths(append([]string{l.GetString(f.opts.OptKey)}, f.opts.Paths...), f.opts.LookupEnv)
+}
+
+func (f *fileProvider) stat(path string) (os.FileInfo, error) {
+	return statFile(f.opts.FS, path)
+}
+
+// Layered loads options from a stack of config files, merging their values.
+// Unlike YAML, JSON, and TOML, the paths are given in order of increasing
+// precedence: a value from a later file overrides the same value from an
+// earlier file. The file at OptKey (e.g. from a --config flag) has the highest
+// precedence of all. Files which don't exist are skipped.
+//
+// The format of each file is determined by its extension, so YAML, JSON,
+// and TOML files may be mixed: .yaml, .yml, .json, or .toml.
+//
+// See DefaultLayers for a common set of paths.
+func Layered(opts FileOpts) Provider {
+	return &layeredProvider{fileDefaults(opts)}
+}
+
+// DefaultLayers returns FileOpts describing a common stack of config files
+// for the given app name, from lowest to highest precedence:
+//
+//   /etc/<appname>/config.yaml
+//   $XDG_CONFIG_HOME/<appname>/config.yaml (defaults to $HOME/.config)
+//   ./config.toml, ./config.json, ./config.yml, ./config.yaml
+//   the path given by the "config" option, e.g. --config
+//
+// $XDG_CONFIG_HOME is expanded with FileOpts.LookupEnv, like other
+// variables, and the path is skipped if neither it nor $HOME is set.
+//
+// The files in the working directory are the paths of DefaultTOML,
+// DefaultJSON, and DefaultYAML, which AutoCobra loaded before it used
+// DefaultLayers.
+func DefaultLayers(appname string) FileOpts {
+	return FileOpts{
+		Paths: []string{
+			filepath.Join("/etc", appname, "config.yaml"),
+			filepath.Join("$XDG_CONFIG_HOME", appname, "config.yaml"),
+			"config.toml",
+			"config.json",
+			"config.yml",
+			"config.yaml",
+		},
+		OptKey: []string{"config"},
+	}
+}
+
+type layeredProvider struct {
+	opts FileOpts
+}
+
+func (f *layeredProvider) String() string {
+	return "file"
+}
+
+func (f *layeredProvider) Provide(l *Loader) error {
+	paths := f.files(l)
+
+	// Loader doesn't override values which are already set,
+	// so the files are loaded from highest to lowest precedence.
+	for i := len(paths) - 1; i >= 0; i-- {
+		path := paths[i]
+		if !exists(f.opts.FS, path) {
+			continue
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		unm, ok := unmarshalers[ext]
+		if !ok {
+			return fmt.Errorf("loading %s: unknown config file format %q", path, ext)
+		}
 
-		data := map[string]interface{}{}
-		err = f.unm(b, &data)
+		err := loadFile(l, f.opts.FS, path, unm)
 		if err != nil {
 			return err
 		}
+	}
+	return nil
+}
+
+func (f *layeredProvider) files(l *Loader) []string {
+	return expandPaths(append(f.opts.Paths[:len(f.opts.Paths):len(f.opts.Paths)], l.GetString(f.opts.OptKey)), f.opts.LookupEnv)
+}
+
+func (f *layeredProvider) stat(path string) (os.FileInfo, error) {
+	return statFile(f.opts.FS, path)
+}
+
+// expandPaths expands environment variables in the given paths,
+// and removes empty paths. $XDG_CONFIG_HOME defaults to $HOME/.config,
+// and paths containing it are removed if neither is set.
+func expandPaths(paths []string, lookup func(string) (string, bool)) []string {
+	var expanded []string
+	for _, path := range paths {
+		skip := false
+		path = os.Expand(path, func(k string) string {
+			v, _ := lookup(k)
+			if k == "XDG_CONFIG_HOME" {
+				v = xdgConfigHome(lookup)
+				skip = v == ""
+			}
+			return v
+		})
+		if path != "" && !skip {
+			expanded = append(expanded, path)
+		}
+	}
+	return expanded
+}
+
+// xdgConfigHome returns $XDG_CONFIG_HOME, which defaults to $HOME/.config,
+// or "" if neither is set.
+func xdgConfigHome(lookup func(string) (string, bool)) string {
+	if dir, _ := lookup("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	if home, _ := lookup("HOME"); home != "" {
+		return filepath.Join(home, ".config")
+	}
+	return ""
+}
+
+// unmarshalers maps a file extension to an unmarshaler.
+var unmarshalers = map[string]unmarshaler{
+	".yaml": unmarshalYAML,
+	".yml":  unmarshalYAML,
+	".json": json.Unmarshal,
+	".toml": toml.Unmarshal,
+}
 
-		flatten2(data, l, nil)
+// loadFile reads and unmarshals a config file from fsys,
+// or the OS file system if fsys is nil, setting option values
+// for the leaves.
+func loadFile(l *Loader, fsys fs.FS, path string, unm unmarshaler) error {
+	b, err := readFile(fsys, path)
+	if err != nil {
+		return err
 	}
 
+	data := map[string]interface{}{}
+	err = unm(b, &data)
+	if err != nil {
+		return fmt.Errorf("loading %s: %v", path, err)
+	}
+
+	flatten2(data, l, nil, func(key []string) string {
+		if line := lineOf(b, key); line > 0 {
+			return fmt.Sprintf("%s:%d", path, line)
+		}
+		return path
+	})
 	return nil
 }
 
 // wrap yaml.Unmarshal because they changed the interface.
 func unmarshalYAML(b []byte, i interface{}) error {
-  return yaml.Unmarshal(b, i)
+	return yaml.Unmarshal(b, i)
 }
 
 type unmarshaler func([]byte, interface{}) error
+
+// fsPath converts a file path to a path in an fs.FS, which is unrooted
+// and slash-separated, e.g. "/etc/app/config.yaml" is "etc/app/config.yaml".
+func fsPath(path string) string {
+	path = strings.TrimPrefix(path, filepath.VolumeName(path))
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "/")
+}
+
+// readFile reads a file from fsys, or the OS file system if fsys is nil.
+func readFile(fsys fs.FS, path string) ([]byte, error) {
+	if fsys == nil {
+		return ioutil.ReadFile(path)
+	}
+	return fs.ReadFile(fsys, fsPath(path))
+}
+
+// statFile describes a file in fsys, or the OS file system if fsys is nil.
+func statFile(fsys fs.FS, path string) (os.FileInfo, error) {
+	if fsys == nil {
+		return os.Stat(path)
+	}
+	return fs.Stat(fsys, fsPath(path))
+}
@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLayered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cli-layered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		err := ioutil.WriteFile(p, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	system := write("system.yaml", "name: system\naddr: :8080\nport: 1\n")
+	user := write("user.json", `{"addr": ":9090", "port": 2}`)
+	flag := write("flag.toml", "port = 3\n")
+
+	var name, addr, config string
+	var port int
+	opts := []*Opt{
+		{Key: []string{"name"}, Value: &name},
+		{Key: []string{"addr"}, Value: &addr},
+		{Key: []string{"port"}, Value: &port},
+		{Key: []string{"config"}, Value: &config},
+	}
+	config = flag
+
+	l := NewLoader(opts, Layered(FileOpts{
+		Paths:  []string{system, filepath.Join(dir, "missing.yaml"), user},
+		OptKey: []string{"config"},
+	}))
+	l.Load()
+
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+	if name != "system" || addr != ":9090" || port != 3 {
+		t.Errorf("unexpected values: %q %q %d", name, addr, port)
+	}
+
+	loc := l.Source([]string{"name"}).Location
+	if loc != system+":1" {
+		t.Errorf("unexpected location: %s", loc)
+	}
+	loc = l.Source([]string{"port"}).Location
+	if loc != flag+":1" {
+		t.Errorf("unexpected location: %s", loc)
+	}
+}
+
+func TestStructList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cli-struct-list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"config.yaml": "backends:\n  - addr: a:80\n    maxweight: 2\n  - addr: b:80\n",
+		"config.toml": "[[backends]]\naddr = \"a:80\"\nmaxweight = 2\n\n[[backends]]\naddr = \"b:80\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var backends []testBackend
+		opts := []*Opt{testBackendOpt(&backends)}
+		l := NewLoader(opts, Layered(FileOpts{Paths: []string{path}}))
+		l.Load()
+
+		if errs := l.Errors(); errs != nil {
+			t.Fatal(name, errs)
+		}
+		if len(backends) != 2 || backends[0].Addr != "a:80" || backends[0].MaxWeight != 2 || backends[1].Addr != "b:80" {
+			t.Errorf("%s: unexpected value %+v", name, backends)
+		}
+	}
+}
+
+func TestFileFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/app/config.yaml":  {Data: []byte("name: system\n")},
+		"home/app/config.json": {Data: []byte(`{"addr": ":9090"}`)},
+		"defaults.yaml":        {Data: []byte("name: default\naddr: :8080\nport: 80\n")},
+	}
+	lookup := func(k string) (string, bool) {
+		if k == "HOME" {
+			return "/home", true
+		}
+		return "", false
+	}
+
+	var name, addr string
+	var port int
+	opts := []*Opt{
+		{Key: []string{"name"}, Value: &name},
+		{Key: []string{"addr"}, Value: &addr},
+		{Key: []string{"port"}, Value: &port},
+	}
+	l := NewLoader(opts,
+		Layered(FileOpts{
+			Paths:     []string{"/etc/app/config.yaml", "$HOME/app/config.json", "missing.yaml"},
+			FS:        fsys,
+			LookupEnv: lookup,
+		}),
+		YAML(FileOpts{Paths: []string{"defaults.yaml"}, FS: fsys}),
+	)
+	l.Load()
+
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+	if name != "system" || addr != ":9090" || port != 80 {
+		t.Errorf("unexpected values: %q %q %d", name, addr, port)
+	}
+	if loc := l.Source([]string{"addr"}).Location; loc != "/home/app/config.json" {
+		t.Errorf("unexpected location: %s", loc)
+	}
+	if stat := l.fileStats()["/home/app/config.json"]; !stat.exists {
+		t.Error("expected file stat from FS")
+	}
+}
+
+func TestDefaultLayers(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.toml": {Data: []byte("name = \"toml\"\nport = 1\n")},
+		"config.json": {Data: []byte(`{"name": "json", "addr": ":9090"}`)},
+		"config.yml":  {Data: []byte("name: yml\n")},
+	}
+
+	var name, addr string
+	var port int
+	opts := []*Opt{
+		{Key: []string{"name"}, Value: &name},
+		{Key: []string{"addr"}, Value: &addr},
+		{Key: []string{"port"}, Value: &port},
+	}
+	layers := DefaultLayers("app")
+	layers.FS = fsys
+	l := NewLoader(opts, Layered(layers))
+	l.Load()
+
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+	if name != "yml" || addr != ":9090" || port != 1 {
+		t.Errorf("unexpected values: %q %q %d", name, addr, port)
+	}
+}
+
+func TestExpandXDGConfigHome(t *testing.T) {
+	tests := []struct {
+		env    map[string]string
+		expect []string
+	}{
+		{map[string]string{"XDG_CONFIG_HOME": "/cfg", "HOME": "/home"}, []string{"/cfg/app/config.yaml"}},
+		{map[string]string{"XDG_CONFIG_HOME": "", "HOME": "/home"}, []string{"/home/.config/app/config.yaml"}},
+		{map[string]string{}, nil},
+	}
+	for _, test := range tests {
+		lookup := func(k string) (string, bool) {
+			v, ok := test.env[k]
+			return v, ok
+		}
+		got := expandPaths([]string{"$XDG_CONFIG_HOME/app/config.yaml"}, lookup)
+		if !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("%v: expected %v, got %v", test.env, test.expect, got)
+		}
+	}
+}
+
+func TestFileMaps(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml": {Data: []byte("tags:\n  a: b\nlimits:\n  cpu: 2\n")},
+		"config.json": {Data: []byte(`{"tags": {"a": "b"}, "limits": {"cpu": 2}}`)},
+		"config.toml": {Data: []byte("[tags]\na = \"b\"\n\n[limits]\ncpu = 2\n")},
+	}
+
+	for _, path := range []string{"config.yaml", "config.json", "config.toml"} {
+		var tags map[string]string
+		var limits map[string]int
+		opts := []*Opt{
+			{Key: []string{"tags"}, Value: &tags},
+			{Key: []string{"limits"}, Value: &limits},
+		}
+		l := NewLoader(opts, Layered(FileOpts{Paths: []string{path}, FS: fsys}))
+		l.Load()
+
+		if errs := l.Errors(); errs != nil {
+			t.Fatal(path, errs)
+		}
+		if !reflect.DeepEqual(tags, map[string]string{"a": "b"}) {
+			t.Errorf("%s: unexpected tags %v", path, tags)
+		}
+		if !reflect.DeepEqual(limits, map[string]int{"cpu": 2}) {
+			t.Errorf("%s: unexpected limits %v", path, limits)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errNotFound is returned by requestJSON when the server responds with 404.
+var errNotFound = fmt.Errorf("not found")
+
+// requestJSON sends a request and decodes the JSON response body into "dest".
+// Providers which load values from HTTP APIs (e.g. Consul) use this.
+func requestJSON(req *http.Request, timeout time.Duration, dest interface{}) error {
+	b, err := doRequest(req, timeout)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, dest)
+	if err != nil {
+		return fmt.Errorf("decoding response from %s: %v", req.URL, err)
+	}
+	return nil
+}
+
+// doRequest sends a request and returns the response body.
+// Non-200 responses are returned as an error.
+func doRequest(req *http.Request, timeout time.Duration) ([]byte, error) {
+	client := http.Client{
+		Timeout: timeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{req.URL.String(), resp.StatusCode, resp.Status}
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// statusError is returned by doRequest when the server responds
+// with a status other than 200 or 404.
+type statusError struct {
+	url    string
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected response status from %s: %s", e.url, e.status)
+}
+
+// httpAddr adds a default "http://" scheme to an address, if needed,
+// and removes any trailing slash.
+func httpAddr(addr string) string {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return strings.TrimSuffix(addr, "/")
+}
+
+// splitPath splits a "/" separated key path, such as a Consul or etcd key,
+// into an option key, after removing the given prefix.
+func splitPath(path, prefix string) []string {
+	path = strings.TrimPrefix(path, prefix)
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
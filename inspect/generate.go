@@ -51,24 +51,41 @@ func Generate(pkg *Package, tpl *template.Template) (err error) {
 
 type uniqImports map[string]string
 
-func (u uniqImports) Uniq(pkgname string) string {
+// Uniq returns a unique import name for the package at the given path,
+// reusing the existing name if the package is already imported.
+func (u uniqImports) Uniq(pkgname, path string) string {
 	try := pkgname
 	i := 1
 	for {
-		_, ok := u[pkgname]
-		if !ok {
+		p, ok := u[try]
+		if !ok || p == path {
 			break
 		}
-		try = fmt.Sprint("%s%d", pkgname, i)
+		try = fmt.Sprintf("%s%d", pkgname, i)
+		i++
 	}
 	return try
 }
 
+// qualifier returns a types.Qualifier which adds the imports needed
+// to refer to types from outside the given package.
+func (u uniqImports) qualifier(pkgPath string) types.Qualifier {
+	return func(p *types.Package) string {
+		if p.Path() == pkgPath {
+			return ""
+		}
+		name := u.Uniq(p.Name(), p.Path())
+		u[name] = p.Path()
+		return name
+	}
+}
+
 func TemplateVars(pkg *Package) map[string]interface{} {
 	var defs []tplVars
 
 	imports := uniqImports{
-		"cli": "github.com/buchanae/cli",
+		"cli":     "github.com/buchanae/cli",
+		"context": "context",
 	}
 
 	for _, def := range pkg.Funcs {
@@ -77,6 +94,9 @@ func TemplateVars(pkg *Package) map[string]interface{} {
 			FuncName:     name,
 			FuncNamePriv: makePrivate(name),
 			Doc:          def.Doc,
+			HasComplete:  def.HasComplete,
+			HasContext:   def.HasContext,
+			ReturnsError: def.ReturnsError,
 		}
 
 		for i, arg := range def.Args {
@@ -86,7 +106,7 @@ func TemplateVars(pkg *Package) map[string]interface{} {
 				path := tn.Pkg().Path()
 				name := tn.Name()
 				if path != def.Package {
-					pkgname := imports.Uniq(tn.Pkg().Name())
+					pkgname := imports.Uniq(tn.Pkg().Name(), path)
 					imports[pkgname] = path
 					typeName = pkgname + "." + name
 				}
@@ -113,25 +133,26 @@ func TemplateVars(pkg *Package) map[string]interface{} {
 				vars.OptsType = name
 				vars.DefaultOptsName = "Default" + name + "()"
 			} else {
-				pkgname := imports.Uniq(tn.Pkg().Name())
+				pkgname := imports.Uniq(tn.Pkg().Name(), path)
 				imports[pkgname] = path
 				vars.OptsType = pkgname + "." + name
 				vars.DefaultOptsName = pkgname + ".Default" + name + "()"
 			}
 		}
 
-		for _, opt := range def.Opts {
-			vars.Opts = append(vars.Opts, optVars{
-				Key:        opt.Key,
-				KeyJoined:  strings.Join(opt.Key, "."),
-				Type:       opt.Type.String(),
-				Doc:        opt.Doc,
-				Short:      reflect.StructTag(opt.Tag).Get("short"),
-				Synopsis:   doc.Synopsis(opt.Doc),
-				Deprecated: "",
-				Hidden:     false,
+		q := imports.qualifier(def.Package)
+		if def.Returns != nil {
+			// Returns is only used in a string, so it mustn't add imports.
+			vars.Returns = types.TypeString(def.Returns, func(p *types.Package) string {
+				if p.Path() == def.Package {
+					return ""
+				}
+				return p.Name()
 			})
 		}
+		for _, opt := range def.Opts {
+			vars.Opts = append(vars.Opts, newOptVars(opt, q))
+		}
 		defs = append(defs, vars)
 	}
 
@@ -142,6 +163,36 @@ func TemplateVars(pkg *Package) map[string]interface{} {
 	}
 }
 
+func newOptVars(opt *Leaf, q types.Qualifier) optVars {
+	v := optVars{
+		Key:        opt.Key,
+		KeyJoined:  strings.Join(opt.Key, "."),
+		Sel:        strings.Join(opt.Selector, "."),
+		Type:       opt.Type.String(),
+		Doc:        opt.Doc,
+		Short:      reflect.StructTag(opt.Tag).Get("short"),
+		Tag:        opt.Tag,
+		Synopsis:   doc.Synopsis(opt.Doc),
+		Deprecated: "",
+		Hidden:     false,
+	}
+	for _, f := range opt.Fields {
+		v.Fields = append(v.Fields, newOptVars(f, q))
+	}
+	// The qualifier adds an import for the type,
+	// which is only used by options with pointer parents.
+	if len(opt.PtrParents) > 0 {
+		v.ValueType = types.TypeString(opt.Type, q)
+	}
+	for _, p := range opt.PtrParents {
+		v.PtrParents = append(v.PtrParents, ptrParentVars{
+			Sel:  strings.Join(p.Selector, "."),
+			Type: types.TypeString(p.Elem, q),
+		})
+	}
+	return v
+}
+
 func makePrivate(s string) string {
 	return strings.ToLower(s[:1]) + s[1:]
 }
@@ -153,6 +204,22 @@ type optVars struct {
 	Hidden                    bool
 	Type                      string
 	Short                     string
+	Tag                       string
+	Fields                    []optVars
+	// Sel is the Go selector of the field, e.g. "Server.TLS.Cert".
+	Sel string
+	// ValueType is the Go type of the field, qualified by
+	// the generated imports, e.g. "*url.URL". Only set if the
+	// field has pointer parents.
+	ValueType  string
+	PtrParents []ptrParentVars
+}
+
+// ptrParentVars describes a pointer to a struct on the path to an option,
+// which the generated code allocates when the option is set.
+type ptrParentVars struct {
+	Sel  string
+	Type string
 }
 
 type tplVars struct {
@@ -168,6 +235,13 @@ type tplVars struct {
 
 	HasArgs bool
 	Args    []argVars
+
+	HasComplete bool
+	HasContext  bool
+
+	// Returns is the type of the value returned by the function, if any.
+	Returns      string
+	ReturnsError bool
 }
 
 type argVars struct {
@@ -0,0 +1,100 @@
+package inspect
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// genSource declares options with types from other packages,
+// with and without pointer parents.
+const genSource = `package gen
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"time"
+)
+
+type Opt struct {
+	Out     io.Writer
+	Timeout time.Duration
+	Proxy   *ProxyOpt
+}
+
+type ProxyOpt struct {
+	URL *url.URL
+}
+
+func DefaultOpt() Opt {
+	return Opt{Out: os.Stdout}
+}
+
+// Run runs.
+func Run(opt Opt) {
+}
+`
+
+// tempPackage writes a package containing "src" in a temporary directory
+// under testdata, which is removed by the returned func.
+func tempPackage(t *testing.T, src string) (string, func()) {
+	t.Helper()
+	err := os.MkdirAll("testdata", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("testdata", "gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "gen_cli.go"), []byte(src), 0644)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestGenerateImports(t *testing.T) {
+	dir, cleanup := tempPackage(t, genSource)
+	defer cleanup()
+
+	pkg, err := Inspect([]string{"./" + dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Generate(pkg, DefaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("go", "build", "-mod=readonly", "./"+dir).CombinedOutput()
+	if err != nil {
+		b, _ := ioutil.ReadFile(filepath.Join(dir, "generated_specs.go"))
+		t.Fatalf("generated code doesn't compile: %v\n%s\n%s", err, out, b)
+	}
+}
+
+func TestUniqImports(t *testing.T) {
+	imports := uniqImports{}
+	tests := []struct {
+		name, path, expect string
+	}{
+		{"log", "log", "log"},
+		{"log", "github.com/sirupsen/log", "log1"},
+		{"log", "log", "log"},
+		{"log", "example.com/log", "log2"},
+		{"log", "github.com/sirupsen/log", "log1"},
+	}
+	for _, test := range tests {
+		got := imports.Uniq(test.name, test.path)
+		if got != test.expect {
+			t.Errorf("%s: expected import name %q, got %q", test.path, test.expect, got)
+		}
+		imports[got] = test.path
+	}
+}
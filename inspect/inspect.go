@@ -74,6 +74,10 @@ func Inspect(packages []string) (*Package, error) {
 		}
 	}
 
+	// A function named e.g. "CompleteServerRun" completes the arguments
+	// of ServerRun, rather than being a command itself.
+	funcs = pairCompleters(funcs, info.Pkg.Scope())
+
 	if len(funcs) == 0 {
 		return nil, fmt.Errorf("no CLI functions found")
 	}
@@ -91,6 +95,21 @@ func Inspect(packages []string) (*Package, error) {
 		}
 		sig := z.Type().(*types.Signature)
 
+		// Functions may return an error, a value, or both.
+		res := sig.Results()
+		switch {
+		case res.Len() == 0:
+		case res.Len() == 1 && isError(res.At(0).Type()):
+			def.ReturnsError = true
+		case res.Len() == 1:
+			def.Returns = res.At(0).Type()
+		case res.Len() == 2 && isError(res.At(1).Type()):
+			def.Returns = res.At(0).Type()
+			def.ReturnsError = true
+		default:
+			return nil, fmt.Errorf("%s: unsupported results, expected error, (T, error), or T", def.Name)
+		}
+
 		params := sig.Params()
 		for i := 0; i < params.Len(); i++ {
 			p := params.At(i)
@@ -98,6 +117,15 @@ func Inspect(packages []string) (*Package, error) {
 			isOpt := p.Name() == "opt"
 			variadic := sig.Variadic() && i == params.Len()-1
 
+			// A leading context.Context is passed by the generated Run method.
+			if isContext(p.Type()) {
+				if i != 0 {
+					return nil, fmt.Errorf("%s: context.Context must be the first argument", def.Name)
+				}
+				def.HasContext = true
+				continue
+			}
+
 			if isOpt {
 				if variadic {
 					continue
@@ -126,8 +154,12 @@ func Inspect(packages []string) (*Package, error) {
 				//      might consider a non-pointer value.
 				def.HasDefaultOpts = defObj != nil
 
-				def.Opts = walk(prog, nil, p.Type(), "", "")
+				w := &walker{prog: prog}
+				def.Opts = w.walk(walkNode{}, p.Type())
 				def.OptsType = nt
+				if w.err != nil {
+					return nil, fmt.Errorf("inspecting options of %s: %v", def.Name, w.err)
+				}
 
 			} else {
 				def.Args = append(def.Args, Arg{
@@ -161,7 +193,71 @@ type Func struct {
 	Opts           []*Leaf
 	OptsType       *types.Named
 	HasDefaultOpts bool
-	Args           []Arg
+	// HasComplete is true if the package has a Complete function
+	// for this function, e.g. CompleteServerRun for ServerRun.
+	HasComplete bool
+	// HasContext is true if the first argument of the function
+	// is a context.Context.
+	HasContext bool
+	Args       []Arg
+	// Returns is the type of the value returned by the function, if any.
+	Returns types.Type
+	// ReturnsError is true if the function returns an error.
+	ReturnsError bool
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+func isContext(t types.Type) bool {
+	nt, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := nt.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "context" && obj.Name() == "Context"
+}
+
+// pairCompleters removes Complete functions from the list of commands,
+// marking the function they complete. Functions which don't have the
+// signature of a completer, e.g. a "CompleteTask" command, are kept.
+func pairCompleters(funcs []*Func, scope *types.Scope) []*Func {
+	byName := map[string]*Func{}
+	for _, f := range funcs {
+		byName[f.Name] = f
+	}
+
+	var cmds []*Func
+	for _, f := range funcs {
+		if strings.HasPrefix(f.Name, "Complete") && isCompleter(scope.Lookup(f.Name)) {
+			if target, ok := byName[strings.TrimPrefix(f.Name, "Complete")]; ok {
+				target.HasComplete = true
+				continue
+			}
+		}
+		cmds = append(cmds, f)
+	}
+	return cmds
+}
+
+// isCompleter returns true if the object is a function with the signature
+// of cli.Completer's Complete method:
+//
+//   func(args []string, toComplete string) []string
+func isCompleter(obj types.Object) bool {
+	z, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := z.Type().(*types.Signature)
+	params, res := sig.Params(), sig.Results()
+	strs := types.NewSlice(types.Typ[types.String])
+	return !sig.Variadic() &&
+		params.Len() == 2 && res.Len() == 1 &&
+		types.Identical(params.At(0).Type(), strs) &&
+		types.Identical(params.At(1).Type(), types.Typ[types.String]) &&
+		types.Identical(res.At(0).Type(), strs)
 }
 
 type Arg struct {
@@ -185,15 +281,61 @@ type Arg struct {
 type Leaf struct {
 	// The path to the leaf field, e.g. "Root.Sub.SubOne"
 	Key []string
+	// Selector is the path of Go field names to the leaf, which differs
+	// from Key when there are embedded fields, e.g. "Sub.Embedded.SubOne".
+	Selector []string
 	// The comment attached to the leaf, e.g. "Comment for SubOne field."
 	Doc  string
 	Type types.Type
 	Tag  string
+	// Fields holds the leaves of each element of a list of structs,
+	// with keys relative to the element, e.g. "Addr" for []Backend.
+	Fields []*Leaf
+	// PtrParents holds the pointers to structs on the path to the leaf,
+	// from the root down, which may be nil and need to be allocated
+	// before the leaf can be set.
+	PtrParents []PtrParent
+}
+
+// PtrParent describes a pointer to a struct on the path to a leaf,
+// e.g. "TLS" in `TLS *TLSOpts`.
+type PtrParent struct {
+	// Selector is the path of Go field names to the pointer field.
+	Selector []string
+	// Elem is the type the pointer points to, e.g. TLSOpts.
+	Elem types.Type
+}
+
+// walker recursively walks a struct, collecting leaf fields.
+// See the `Leaf` docs for more information about those fields.
+type walker struct {
+	prog *loader.Program
+	// stack holds the named types currently being walked,
+	// in order to detect cycles.
+	stack []*types.Named
+	err   error
+}
+
+// walkNode describes the position of the walker in the tree of fields.
+type walkNode struct {
+	path, sel []string
+	parents   []PtrParent
+	doc, tag  string
 }
 
-// walk recursively walks a struct, collecting leaf fields.
-// See the `leaf` docs for more information about those fields.
-func walk(prog *loader.Program, path []string, t types.Type, doc, tag string) []*Leaf {
+func (w *walker) leaf(n walkNode, t types.Type, fields []*Leaf) []*Leaf {
+	return []*Leaf{{
+		Key:        n.path,
+		Selector:   n.sel,
+		Doc:        n.doc,
+		Type:       t,
+		Tag:        n.tag,
+		Fields:     fields,
+		PtrParents: n.parents,
+	}}
+}
+
+func (w *walker) walk(n walkNode, t types.Type) []*Leaf {
 	var leaves []*Leaf
 
 	switch t := t.(type) {
@@ -205,72 +347,133 @@ func walk(prog *loader.Program, path []string, t types.Type, doc, tag string) []
 				continue
 			}
 
-			subpath := path
+			sub := n
+			sub.sel = newpathS(n.sel, f.Name())
 			if !f.Anonymous() {
-				subpath = newpathS(path, f.Name())
+				sub.path = newpathS(n.path, f.Name())
 			}
-			w := walk(prog, subpath, f.Type(), extractVarDoc(prog, f), t.Tag(i))
-			leaves = append(leaves, w...)
+			sub.doc = extractVarDoc(w.prog, f)
+			sub.tag = t.Tag(i)
+			leaves = append(leaves, w.walk(sub, f.Type())...)
 		}
 
 	case *types.Named:
-		//return walk(prog, path, t.Underlying(), "")
+		// Types which know how to parse themselves are leaves,
+		// even if they're structs, e.g. big.Int or url.URL.
+		if isLeafType(t) {
+			return w.leaf(n, t, nil)
+		}
+
 		switch z := t.Underlying().(type) {
-		case *types.Struct, *types.Named:
-			return walk(prog, path, z, "", "")
+		case *types.Struct:
+			for _, s := range w.stack {
+				if s == t {
+					w.fail(fmt.Errorf("option type %s contains itself at %q, recursive option types aren't supported",
+						t, strings.Join(n.sel, ".")))
+					return nil
+				}
+			}
+			w.stack = append(w.stack, t)
+			defer func() { w.stack = w.stack[:len(w.stack)-1] }()
 
-		case *types.Pointer:
+			n.doc, n.tag = "", ""
+			return w.walk(n, z)
 
-			// TODO this is susceptible to cycles
-			switch el := z.Elem().(type) {
-			case *types.Struct, *types.Named:
-				return walk(prog, path, el, "", "")
-
-			default:
-				leaves = append(leaves, &Leaf{
-					Key:  path,
-					Doc:  doc,
-					Type: t,
-					Tag:  tag,
-				})
-			}
+		case *types.Pointer:
+			return w.walkPointer(n, t, z.Elem())
 
 		case *types.Interface, *types.Basic, *types.Slice, *types.Map, *types.Array:
-			leaves = append(leaves, &Leaf{
-				Key:  path,
-				Doc:  doc,
-				Type: t,
-				Tag:  tag,
-			})
+			return w.leaf(n, t, w.elemFields(z))
 
 		default:
-			// TODO what is going on here? not handling wrapper types?
-			//      what is a value type?
 			// TODO the path in this log message doesn't include the name of the root type.
-			p := strings.Join(path, ".")
+			p := strings.Join(n.path, ".")
 			log.Printf("skipping unhandled type at %q: %v\n", p, t)
 			return nil
 		}
 
-	// TODO this is susceptible to cycles
 	case *types.Pointer:
-		return walk(prog, path, t.Elem(), doc, tag)
+		return w.walkPointer(n, t, t.Elem())
 
 	case *types.Basic, *types.Slice, *types.Map, *types.Array:
-		leaves = append(leaves, &Leaf{
-			Key:  path,
-			Doc:  doc,
-			Type: t,
-			Tag:  tag,
-		})
+		return w.leaf(n, t, w.elemFields(t))
 
 	default:
-		p := strings.Join(path, ".")
+		p := strings.Join(n.path, ".")
 		log.Printf("skipping unhandled type at %q: %v\n", p, t)
 	}
 	return leaves
 }
 
+// walkPointer walks a pointer type "t". Pointers to structs are walked
+// as parents of the struct's leaves, other pointers, e.g. *int, are leaves.
+func (w *walker) walkPointer(n walkNode, t, elem types.Type) []*Leaf {
+	if isLeafType(elem) {
+		return w.leaf(n, t, nil)
+	}
+	if _, ok := elem.Underlying().(*types.Struct); !ok {
+		return w.leaf(n, t, nil)
+	}
+
+	sub := n
+	sub.parents = append(n.parents[:len(n.parents):len(n.parents)], PtrParent{
+		Selector: n.sel,
+		Elem:     elem,
+	})
+	return w.walk(sub, elem)
+}
+
+// elemFields returns the leaves of the element type of a list of structs,
+// or nil if "t" isn't a list of structs.
+func (w *walker) elemFields(t types.Type) []*Leaf {
+	var el types.Type
+	switch z := t.(type) {
+	case *types.Slice:
+		el = z.Elem()
+	case *types.Array:
+		el = z.Elem()
+	default:
+		return nil
+	}
+	if p, ok := el.(*types.Pointer); ok {
+		el = p.Elem()
+	}
+	if isLeafType(el) {
+		return nil
+	}
+	if _, ok := el.Underlying().(*types.Struct); !ok {
+		return nil
+	}
+	return w.walk(walkNode{}, el)
+}
+
+// fail records the first error encountered while walking.
+func (w *walker) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// isLeafType returns true if the type, or a pointer to it, has methods
+// which the cli coercion uses to parse a value, i.e. it implements
+// encoding.TextUnmarshaler, json.Unmarshaler, or flag.Value (and pflag.Value).
+// url.URL is also a leaf, since it's parsed by url.Parse.
+func isLeafType(t types.Type) bool {
+	nt, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	if tn := nt.Obj(); tn.Pkg() != nil && tn.Pkg().Path() == "net/url" && tn.Name() == "URL" {
+		return true
+	}
+
+	ms := types.NewMethodSet(types.NewPointer(t))
+	has := func(name string) bool {
+		return ms.Lookup(nil, name) != nil
+	}
+	return has("UnmarshalText") || has("UnmarshalJSON") || (has("Set") && has("String"))
+}
+
 // extractVarDoc will attempt to return the code comment attached to a var,
 // if it exists.
 func extractVarDoc(prog *loader.Program, f types.Object) string {
@@ -0,0 +1,233 @@
+package inspect
+
+import (
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// completeSource declares Complete functions with and without
+// the signature of a completer.
+const completeSource = `package gen
+
+// Task runs a task.
+func Task(name string) {
+}
+
+// CompleteTask completes a task, and is a command.
+func CompleteTask(name string) error {
+	return nil
+}
+
+// Run runs.
+func Run(name string) {
+}
+
+func CompleteRun(args []string, toComplete string) []string {
+	return nil
+}
+`
+
+func TestPairCompleters(t *testing.T) {
+	dir, cleanup := tempPackage(t, completeSource)
+	defer cleanup()
+
+	pkg, err := Inspect([]string{"./" + dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := map[string]*Func{}
+	for _, f := range pkg.Funcs {
+		funcs[f.Name] = f
+	}
+	if len(funcs) != 3 || funcs["Task"] == nil || funcs["CompleteTask"] == nil || funcs["Run"] == nil {
+		t.Fatalf("expected commands Task, CompleteTask, and Run, got %v", funcs)
+	}
+	if funcs["Task"].HasComplete {
+		t.Error("expected Task to have no completer")
+	}
+	if !funcs["Run"].HasComplete {
+		t.Error("expected Run to be completed by CompleteRun")
+	}
+}
+
+func TestInspectCycle(t *testing.T) {
+	tests := []struct {
+		name, types, expect string
+	}{
+		{
+			"pointer",
+			"type Opt struct { Name string; Next *Opt }",
+			`.Opt contains itself at "Next"`,
+		},
+		{
+			"indirect",
+			"type Opt struct { Sub Sub }\ntype Sub struct { Parent *Opt }",
+			`.Opt contains itself at "Sub.Parent"`,
+		},
+		{
+			"slice",
+			"type Opt struct { Children []Opt }",
+			".Opt contains itself",
+		},
+		{
+			"siblings",
+			"type Opt struct { A Sub; B *Sub }\ntype Sub struct { Name string }",
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		src := "package gen\n\n" + test.types + "\n\nfunc Run(opt Opt) {}\n"
+		dir, cleanup := tempPackage(t, src)
+		_, err := Inspect([]string{"./" + dir})
+		cleanup()
+
+		switch {
+		case test.expect == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		case test.expect != "" && err == nil:
+			t.Errorf("%s: expected error %q", test.name, test.expect)
+		case test.expect != "" && !strings.Contains(err.Error(), test.expect):
+			t.Errorf("%s: expected error %q, got %q", test.name, test.expect, err)
+		}
+	}
+}
+
+// leafSource declares options with struct and slice types
+// which parse themselves, and so are single options.
+const leafSource = `package gen
+
+import (
+	"net"
+	"net/url"
+	"time"
+)
+
+type Opt struct {
+	Timeout  time.Duration
+	Addr     net.IP
+	Endpoint url.URL
+	Proxy    *url.URL
+	Deadline time.Time
+}
+
+func Run(opt Opt) {
+}
+`
+
+func TestInspectLeafTypes(t *testing.T) {
+	dir, cleanup := tempPackage(t, leafSource)
+	defer cleanup()
+
+	pkg, err := Inspect([]string{"./" + dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, typ string
+	}{
+		{"Timeout", "time.Duration"},
+		{"Addr", "net.IP"},
+		{"Endpoint", "net/url.URL"},
+		{"Proxy", "*net/url.URL"},
+		{"Deadline", "time.Time"},
+	}
+
+	opts := pkg.Funcs[0].Opts
+	if len(opts) != len(tests) {
+		for _, o := range opts {
+			t.Log(strings.Join(o.Key, "."), o.Type)
+		}
+		t.Fatalf("expected %d options, got %d", len(tests), len(opts))
+	}
+	for i, test := range tests {
+		key := strings.Join(opts[i].Key, ".")
+		if key != test.key || opts[i].Type.String() != test.typ {
+			t.Errorf("expected option %s %s, got %s %s", test.key, test.typ, key, opts[i].Type)
+		}
+	}
+}
+
+func TestInspectReturns(t *testing.T) {
+	tests := []struct {
+		results, returns string
+		returnsError     bool
+		err              string
+	}{
+		{"", "", false, ""},
+		{"error", "", true, ""},
+		{"string", "string", false, ""},
+		{"(*Report, error)", "*gen.Report", true, ""},
+		{"(string, int)", "", false, "unsupported results"},
+		{"(error, string)", "", false, "unsupported results"},
+	}
+
+	for _, test := range tests {
+		src := "package gen\n\ntype Report struct{}\n\nfunc Run() " + test.results + " {\n\tpanic(0)\n}\n"
+		dir, cleanup := tempPackage(t, src)
+		pkg, err := Inspect([]string{"./" + dir})
+		cleanup()
+
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: expected error %q, got %v", test.results, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.results, err)
+			continue
+		}
+
+		f := pkg.Funcs[0]
+		returns := ""
+		if f.Returns != nil {
+			// Strip the temporary package path, e.g. "./testdata/gen123.Report".
+			returns = types.TypeString(f.Returns, func(p *types.Package) string { return p.Name() })
+		}
+		if returns != test.returns || f.ReturnsError != test.returnsError {
+			t.Errorf("%q: expected Returns %q and ReturnsError %v, got %q and %v",
+				test.results, test.returns, test.returnsError, returns, f.ReturnsError)
+		}
+	}
+}
+
+func TestInspectContext(t *testing.T) {
+	tests := []struct {
+		params     string
+		hasContext bool
+		args       int
+		err        string
+	}{
+		{"ctx context.Context, name string", true, 1, ""},
+		{"name string", false, 1, ""},
+		{"name string, ctx context.Context", false, 0, "context.Context must be the first argument"},
+	}
+
+	for _, test := range tests {
+		src := "package gen\n\nimport \"context\"\n\nvar _ context.Context\n\nfunc Run(" + test.params + ") {\n}\n"
+		dir, cleanup := tempPackage(t, src)
+		pkg, err := Inspect([]string{"./" + dir})
+		cleanup()
+
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: expected error %q, got %v", test.params, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.params, err)
+			continue
+		}
+
+		f := pkg.Funcs[0]
+		if f.HasContext != test.hasContext || len(f.Args) != test.args {
+			t.Errorf("%q: expected HasContext %v and %d args, got %v and %d",
+				test.params, test.hasContext, test.args, f.HasContext, len(f.Args))
+		}
+	}
+}
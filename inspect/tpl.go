@@ -36,8 +36,9 @@ type {{ .FuncNamePriv }}Spec struct {
   }
 }
 
-func (cmd *{{ .FuncNamePriv }}Spec) Run() {
-  {{ .FuncName }}(
+func (cmd *{{ .FuncNamePriv }}Spec) Run(ctx context.Context) (interface{}, error) {
+  {{ if .Returns }}return {{ else if .ReturnsError }}return nil, {{ end -}}
+  {{ .FuncName }}({{ if .HasContext }}ctx,{{ end }}
   {{- if .HasOpts }}
     cmd.opt,
   {{ end -}}
@@ -48,8 +49,23 @@ func (cmd *{{ .FuncNamePriv }}Spec) Run() {
     cmd.args.arg{{ .Idx }},
     {{- end }}
   {{ end -}}
-  )
+  ){{ if and .Returns (not .ReturnsError) }}, nil{{ end }}
+  {{- if not (or .Returns .ReturnsError) }}
+  return nil, nil
+  {{- end }}
+}
+
+{{ if .HasOpts -}}
+func (cmd *{{ .FuncNamePriv }}Spec) Opt() interface{} {
+  return &cmd.opt
+}
+{{- end }}
+
+{{ if .HasComplete -}}
+func (cmd *{{ .FuncNamePriv }}Spec) Complete(args []string, toComplete string) []string {
+  return Complete{{ .FuncName }}(args, toComplete)
 }
+{{- end }}
 
 func (cmd *{{ .FuncNamePriv }}Spec) Cmd() *cli.Cmd {
   if cmd.cmd != nil {
@@ -58,6 +74,12 @@ func (cmd *{{ .FuncNamePriv }}Spec) Cmd() *cli.Cmd {
   cmd.cmd = &cli.Cmd{
     RawName:   {{ .FuncName | printf "%q" }},
     RawDoc: {{ .Doc | printf "%q" }},
+    {{ if .Returns -}}
+    Returns: {{ .Returns | printf "%q" }},
+    {{ end -}}
+    {{ if .HasContext -}}
+    HasContext: true,
+    {{ end -}}
     Args: []*cli.Arg{
       {{ range .Args -}}
       {
@@ -73,10 +95,38 @@ func (cmd *{{ .FuncNamePriv }}Spec) Cmd() *cli.Cmd {
       {
         Key: {{ .Key | printf "%#v" }},
         RawDoc: {{ .Doc | printf "%q" }},
-        Value: &cmd.opt.{{ .KeyJoined }},
-        DefaultValue: cmd.opt.{{ .KeyJoined }},
+        {{ if .PtrParents -}}
+        Value: (*{{ .ValueType }})(nil),
+        Ref: func(alloc bool) interface{} {
+          {{ range .PtrParents -}}
+          if cmd.opt.{{ .Sel }} == nil {
+            if !alloc {
+              return nil
+            }
+            cmd.opt.{{ .Sel }} = new({{ .Type }})
+          }
+          {{ end -}}
+          return &cmd.opt.{{ .Sel }}
+        },
+        {{- else -}}
+        Value: &cmd.opt.{{ .Sel }},
+        DefaultValue: cmd.opt.{{ .Sel }},
+        {{- end }}
         Type: {{ .Type | printf "%q" }},
         Short: {{ .Short | printf "%q" }},
+        Tag: {{ .Tag | printf "%q" }},
+        {{ if .Fields -}}
+        Fields: []*cli.Opt{
+          {{ range .Fields -}}
+          {
+            Key: {{ .Key | printf "%#v" }},
+            RawDoc: {{ .Doc | printf "%q" }},
+            Type: {{ .Type | printf "%q" }},
+            Tag: {{ .Tag | printf "%q" }},
+          },
+          {{- end }}
+        },
+        {{- end }}
       },
       {{- end }}
     },
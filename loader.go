@@ -2,7 +2,9 @@ package cli
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
 )
 
 // Provider is implemented by types which provide option values,
@@ -11,6 +13,53 @@ type Provider interface {
 	Provide(*Loader) error
 }
 
+// Source describes where an option value was loaded from.
+type Source struct {
+	// Key is the key of the option.
+	Key []string
+	// Provider is the provider which set the value.
+	// Provider is nil if the value is the option's default.
+	Provider Provider
+	// Raw is the value given by the provider, before type coercion.
+	Raw interface{}
+	// Location describes where the provider found the value,
+	// e.g. "TODO_DB_PATH", "--db.path", or "config.yaml:12".
+	Location string
+	// Sensitive is true if the option is sensitive,
+	// in which case String redacts the raw value.
+	Sensitive bool
+}
+
+// Redacted is shown in place of the value of a sensitive option.
+const Redacted = "<redacted>"
+
+// String returns a description of the source, e.g.
+// `db.path = "todo.json" (env TODO_DB_PATH)`
+func (s *Source) String() string {
+	from := "default"
+	if s.Provider != nil {
+		from = providerName(s.Provider)
+		if s.Location != "" {
+			from += " " + s.Location
+		}
+	}
+	var raw interface{} = s.Raw
+	if s.Sensitive {
+		raw = Redacted
+	}
+	return fmt.Sprintf("%s = %#v (%s)", DotKey(s.Key), raw, from)
+}
+
+// providerName returns a short, human-friendly name for a provider,
+// e.g. "env" or "yaml". Providers may implement fmt.Stringer to
+// control their name.
+func providerName(p Provider) string {
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p)
+}
+
 // NewLoader returns a Loader instance which is configured
 // to load option values from the given providers.
 func NewLoader(opts []*Opt, providers ...Provider) *Loader {
@@ -35,6 +84,17 @@ type Loader struct {
 	keys      [][]string
 	providers []Provider
 	errors    []error
+	// current is the provider currently being run by Load.
+	current Provider
+	// mu guards option values, sources, and errors, which are
+	// read by Get, Errors, etc. while Reload may be swapping them.
+	mu       sync.RWMutex
+	onChange []func([]Change)
+	// Strict enables stricter checking of unknown keys. Unknown keys
+	// in config files are always reported, but in strict mode, providers
+	// such as Env also look for values with unknown keys, e.g. environment
+	// variables with the app prefix which don't match any option.
+	Strict bool
 	// Coerce can be used to override the type coercion
 	// needed when setting an option value. A coerce function
 	// must set the value. "dst" is always a pointer to the
@@ -44,22 +104,37 @@ type Loader struct {
 }
 
 // Load runs the providers, loading and setting option values.
-// Load is meant to be called only once.
-// TODO this is awkward. The code should make it clear that
-//      it only gets called once, and/or make it safe to load
-//      multiple times.
+// Load is meant to be called only once, before Reload; use Reload to load
+// new values later.
 func (l *Loader) Load() {
 	for _, src := range l.providers {
+		l.setCurrent(src)
 		err := src.Provide(l)
 		if err != nil {
-			l.errors = append(l.errors, err)
+			l.addError(err)
 		}
 	}
+	l.setCurrent(nil)
+}
+
+// setCurrent sets the provider currently being run.
+func (l *Loader) setCurrent(p Provider) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.current = p
+}
+
+func (l *Loader) addError(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, err)
 }
 
 // Errors returns a list of errors encountered during loading.
 func (l *Loader) Errors() []error {
-	return l.errors
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]error(nil), l.errors...)
 }
 
 // Keys returns a list of keys for all options.
@@ -69,9 +144,12 @@ func (l *Loader) Keys() [][]string {
 
 // Get gets the current option value for the given key.
 func (l *Loader) Get(key []string) interface{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	for _, opt := range l.opts {
 		if l.eq(key, opt.Key) {
-			return opt.Value
+			return deref(opt.ref(false))
 		}
 	}
 	return nil
@@ -91,26 +169,162 @@ func (l *Loader) GetString(key []string) string {
 // Set sets an option value for the option at the given key.
 // Once an option is set, it will not be overridden by future
 // calls to Set. Set uses Loader.Coerce to set the value.
-//
-// TODO this is ugly. This checks IsSet, but doesn't set it to true,
-//      that happens somewhere else. The whole set/coerce interface
-//      needs cleanup
 func (l *Loader) Set(key []string, val interface{}) {
+	l.SetFrom(key, val, "")
+}
+
+// SetFrom is like Set, but also records the location the value
+// was loaded from, such as an environment variable name, a flag name,
+// or a file path and line number. The location is stored in the option's
+// Source, along with the current provider and the raw value.
+func (l *Loader) SetFrom(key []string, val interface{}, loc string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	for _, opt := range l.opts {
 		if !l.eq(key, opt.Key) {
 			continue
 		}
 		if opt.IsSet {
-			continue
+			return
+		}
+		// Values with a unit, e.g. "10MB", are parsed before coercion.
+		v, err := val, error(nil)
+		if opt.Unit != "" {
+			v, err = unitValue(opt, val)
+		}
+		if err == nil {
+			err = l.Coerce(opt.ref(true), v)
+		}
+		if err != nil && opt.Sensitive {
+			// Coercion errors usually include the value.
+			err = fmt.Errorf("cannot coerce %s to %s", Redacted, opt.valueType())
 		}
-		err := l.Coerce(opt.Value, val)
 		if err != nil {
+			if from := l.describe(loc); from != "" {
+				err = fmt.Errorf("setting %s from %s: %v", DotKey(key), from, err)
+			} else {
+				err = fmt.Errorf("setting %s: %v", DotKey(key), err)
+			}
 			l.errors = append(l.errors, err)
+			return
+		}
+		opt.IsSet = true
+		opt.Source = &Source{
+			Key:       opt.Key,
+			Provider:  l.current,
+			Raw:       val,
+			Location:  loc,
+			Sensitive: opt.Sensitive,
 		}
 		return
 	}
-	// TODO these errors are missing context, e.g. "in file config.yaml"
-	l.errors = append(l.errors, fmt.Errorf("unknown opt key %v", key))
+	var names []string
+	for _, k := range l.keys {
+		names = append(names, DotKey(k))
+	}
+	name := DotKey(key)
+	l.errors = append(l.errors, &UnknownKeyError{
+		Name:       name,
+		Location:   l.describe(loc),
+		Suggestion: suggest(name, names),
+	})
+}
+
+// describe returns a description of a location for error messages,
+// including the name of the current provider, e.g. "etcd /myapp/server/addr".
+func (l *Loader) describe(loc string) string {
+	if l.current == nil {
+		return loc
+	}
+	name := providerName(l.current)
+	if loc == "" {
+		return name
+	}
+	return name + " " + loc
+}
+
+// UnknownKeyError describes a value which was loaded for a key
+// which doesn't match any option, e.g. a misspelled key in a config file.
+type UnknownKeyError struct {
+	// Name is the unknown key, formatted in the style of the provider,
+	// e.g. "server.adr" or "APP_SERVER_ADR".
+	Name string
+	// Location describes where the key was found, e.g. "config.yaml:3".
+	Location string
+	// Suggestion is the closest valid key, if any.
+	Suggestion string
+}
+
+func (e *UnknownKeyError) Error() string {
+	msg := fmt.Sprintf("unknown opt key %q", e.Name)
+	if e.Location != "" {
+		msg += " in " + e.Location
+	}
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// Source returns information about where the value of the option
+// at the given key was loaded from. If the option was not set by
+// any provider, the returned Source describes the option's default value.
+// Source returns nil if there is no option with the given key.
+func (l *Loader) Source(key []string) *Source {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, opt := range l.opts {
+		if l.eq(key, opt.Key) {
+			return optSource(opt)
+		}
+	}
+	return nil
+}
+
+// Sources returns information about where the value of each option
+// was loaded from, in the same order as the options given to NewLoader.
+// Useful for debug logging and bug reports.
+func (l *Loader) Sources() []*Source {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var sources []*Source
+	for _, opt := range l.opts {
+		sources = append(sources, optSource(opt))
+	}
+	return sources
+}
+
+// ref returns a pointer to the value of the option, see Opt.Ref.
+// Returns nil if the option has a nil parent and "alloc" is false.
+func (opt *Opt) ref(alloc bool) interface{} {
+	if opt.Ref != nil {
+		return opt.Ref(alloc)
+	}
+	return opt.Value
+}
+
+// valueType returns the type of the option's value, e.g. int for *int.
+func (opt *Opt) valueType() reflect.Type {
+	t := reflect.TypeOf(opt.Value)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil
+	}
+	return t.Elem()
+}
+
+func optSource(opt *Opt) *Source {
+	if opt.Source != nil {
+		return opt.Source
+	}
+	return &Source{
+		Key:       opt.Key,
+		Raw:       opt.DefaultValue,
+		Location:  "default",
+		Sensitive: opt.Sensitive,
+	}
 }
 
 // eq returns true if two option keys are equal.
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"os"
+	"testing"
+)
+
+func ExampleLoader_Sources() {
+	os.Setenv("CLI_SERVER_ADDR", ":8081")
+
+	addr := ":8080"
+	name := "example"
+	opts := []*Opt{
+		{Key: []string{"server", "addr"}, Value: &addr, DefaultValue: addr},
+		{Key: []string{"server", "name"}, Value: &name, DefaultValue: name},
+	}
+
+	l := NewLoader(opts, Env("cli"))
+	l.Load()
+
+	for _, src := range l.Sources() {
+		fmt.Println(src)
+	}
+	// Output:
+	// server.addr = ":8081" (env CLI_SERVER_ADDR)
+	// server.name = "example" (default)
+}
+
+func ExampleUnknownKeyError() {
+	path := ""
+	opts := []*Opt{
+		{Key: []string{"db", "path"}, Value: &path},
+	}
+
+	l := NewLoader(opts)
+	l.SetFrom([]string{"db", "pth"}, "todo.db", "config.yaml:3")
+	fmt.Println(l.Errors())
+	// Output:
+	// [unknown opt key "db.pth" in config.yaml:3, did you mean "db.path"?]
+}
+
+func ExampleOpt_sensitive() {
+	os.Setenv("SECRET_DB_PASSWORD", "hunter2")
+	os.Setenv("SECRET_DB_PORT", "not-a-port")
+
+	var password string
+	var port int
+	opts := []*Opt{
+		{Key: []string{"db", "password"}, Value: &password, Tag: `sensitive:"true"`},
+		{Key: []string{"db", "port"}, Value: &port, RawDoc: "Database port.\nSensitive"},
+	}
+	for _, opt := range opts {
+		enrichOpt(opt)
+	}
+
+	l := NewLoader(opts, Env("secret"))
+	l.Load()
+
+	fmt.Println(l.Source([]string{"db", "password"}))
+	fmt.Println(l.Errors())
+	Dump(os.Stdout, opts, DumpOpts{})
+	// Output:
+	// db.password = "<redacted>" (env SECRET_DB_PASSWORD)
+	// [setting db.port from env SECRET_DB_PORT: cannot coerce <redacted> to int]
+	// db:
+	//   password: <redacted>
+	//   # Database port.
+	//   port: <redacted>
+}
+
+func TestPointerOpts(t *testing.T) {
+	type tlsOpt struct {
+		Cert string
+	}
+	opt := struct {
+		Retries *int
+		Debug   *bool
+		TLS     *tlsOpt
+	}{}
+
+	cert := &Opt{
+		Key:   []string{"TLS", "Cert"},
+		Value: (*string)(nil),
+		Ref: func(alloc bool) interface{} {
+			if opt.TLS == nil {
+				if !alloc {
+					return nil
+				}
+				opt.TLS = new(tlsOpt)
+			}
+			return &opt.TLS.Cert
+		},
+	}
+	opts := []*Opt{
+		{Key: []string{"Retries"}, Value: &opt.Retries, DefaultValue: opt.Retries},
+		{Key: []string{"Debug"}, Value: &opt.Debug, DefaultValue: opt.Debug},
+		cert,
+	}
+	Enrich(&Cmd{Opts: opts})
+
+	for _, o := range opts {
+		if o.DefaultString != "unset" {
+			t.Errorf("expected %s default to be unset, got %q", DotKey(o.Key), o.DefaultString)
+		}
+	}
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	l := NewLoader(opts, PFlags(fs, opts, DotKey))
+	if err := fs.Parse([]string{"--retries", "0"}); err != nil {
+		t.Fatal(err)
+	}
+	l.Load()
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+
+	if opt.Retries == nil || *opt.Retries != 0 {
+		t.Errorf("expected retries to be set to zero, got %v", opt.Retries)
+	}
+	if opt.Debug != nil {
+		t.Errorf("expected debug to be unset, got %v", *opt.Debug)
+	}
+	if opt.TLS != nil {
+		t.Error("expected TLS parent to be left nil")
+	}
+	if l.Get(cert.Key) != nil {
+		t.Error("expected nil value for option with nil parent")
+	}
+
+	fs = pflag.NewFlagSet("test", pflag.ContinueOnError)
+	l = NewLoader(opts, PFlags(fs, opts, DotKey))
+	if err := fs.Parse([]string{"--debug", "--tls.cert", "a.pem"}); err != nil {
+		t.Fatal(err)
+	}
+	l.Load()
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+	if opt.Debug == nil || !*opt.Debug {
+		t.Error("expected valueless flag to set debug to true")
+	}
+	if opt.TLS == nil || opt.TLS.Cert != "a.pem" {
+		t.Errorf("expected TLS parent to be allocated, got %+v", opt.TLS)
+	}
+}
@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MetadataOpts describes options related to loading options
+// from a cloud instance metadata service, such as GCE, EC2, or OpenStack.
+//
+// Metadata attributes are matched to options by name, with key parts
+// joined by either "-" or "_", case-insensitive. For example,
+// an attribute named "server-addr" sets the "server.addr" option.
+// Attributes which don't match an option are ignored, since metadata
+// is often shared with other tools.
+//
+// When the metadata service can't be reached, or responds to the first
+// request with 403, 404, or 405, e.g. when running in another cloud which
+// has a metadata service at the same address, the provider does nothing.
+type MetadataOpts struct {
+	// BaseURL is the address of the metadata service.
+	// Defaults to the standard address for each cloud.
+	// Useful for testing with a local stub server.
+	BaseURL string
+	// Prefix is an optional prefix for attribute names,
+	// e.g. a prefix of "myapp" matches "myapp-server-addr".
+	Prefix string
+	// Timeout is the timeout of each request to the metadata service.
+	// The metadata service is local to the instance, so this should be short.
+	// Defaults to 1 second.
+	Timeout time.Duration
+}
+
+func (m MetadataOpts) baseURL(def string) string {
+	if m.BaseURL == "" {
+		return def
+	}
+	return httpAddr(m.BaseURL)
+}
+
+func (m MetadataOpts) timeout() time.Duration {
+	if m.Timeout == 0 {
+		return time.Second
+	}
+	return m.Timeout
+}
+
+// GCE loads options from the custom metadata attributes
+// of a Google Compute Engine instance.
+func GCE(opts MetadataOpts) Provider {
+	return &gce{opts}
+}
+
+type gce struct {
+	opts MetadataOpts
+}
+
+func (g *gce) String() string {
+	return "gce"
+}
+
+func (g *gce) Provide(l *Loader) error {
+	base := g.opts.baseURL("http://metadata.google.internal")
+	u := base + "/computeMetadata/v1/instance/attributes/?recursive=true"
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return fmt.Errorf("gce: %v", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	attrs := map[string]interface{}{}
+	err = requestJSON(req, g.opts.timeout(), &attrs)
+	if unavailable(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("gce: %v", err)
+	}
+
+	setAttributes(l, attrs, g.opts.Prefix)
+	return nil
+}
+
+// OpenStack loads options from the "meta" properties
+// of an OpenStack instance.
+func OpenStack(opts MetadataOpts) Provider {
+	return &openstack{opts}
+}
+
+type openstack struct {
+	opts MetadataOpts
+}
+
+func (o *openstack) String() string {
+	return "openstack"
+}
+
+func (o *openstack) Provide(l *Loader) error {
+	base := o.opts.baseURL("http://169.254.169.254")
+	req, err := http.NewRequest("GET", base+"/openstack/latest/meta_data.json", nil)
+	if err != nil {
+		return fmt.Errorf("openstack: %v", err)
+	}
+
+	var data struct {
+		Meta map[string]interface{} `json:"meta"`
+	}
+	err = requestJSON(req, o.opts.timeout(), &data)
+	if unavailable(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("openstack: %v", err)
+	}
+
+	setAttributes(l, data.Meta, o.opts.Prefix)
+	return nil
+}
+
+// EC2 loads options from the user-data of an Amazon EC2 instance,
+// using the v2 instance metadata service (IMDSv2). The user-data
+// must be a YAML or JSON document, such as:
+//
+//   server:
+//     addr: :8080
+//
+// User-data which isn't a YAML or JSON document, such as a shell script,
+// is ignored. Keys which don't match an option are also ignored, so that
+// the user-data can be shared with other tools, such as cloud-init.
+func EC2(opts MetadataOpts) Provider {
+	return &ec2{opts}
+}
+
+type ec2 struct {
+	opts MetadataOpts
+}
+
+func (e *ec2) String() string {
+	return "ec2"
+}
+
+func (e *ec2) Provide(l *Loader) error {
+	base := e.opts.baseURL("http://169.254.169.254")
+	timeout := e.opts.timeout()
+
+	// IMDSv2 requires a session token.
+	req, err := http.NewRequest("PUT", base+"/latest/api/token", nil)
+	if err != nil {
+		return fmt.Errorf("ec2: %v", err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+
+	token, err := doRequest(req, timeout)
+	if unavailable(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ec2: getting metadata token: %v", err)
+	}
+
+	req, err = http.NewRequest("GET", base+"/latest/user-data", nil)
+	if err != nil {
+		return fmt.Errorf("ec2: %v", err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+
+	b, err := doRequest(req, timeout)
+	if err == errNotFound {
+		// The instance has no user-data.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ec2: getting user-data: %v", err)
+	}
+
+	data := map[string]interface{}{}
+	if unmarshalYAML(b, &data) != nil {
+		return nil
+	}
+
+	for _, key := range l.Keys() {
+		val, ok := lookupPath(data, key)
+		if ok {
+			l.SetFrom(key, val, "user-data")
+		}
+	}
+	return nil
+}
+
+// unavailable returns true if the error from the first request to a metadata
+// service means we're not running in that cloud: the server couldn't be
+// reached, or it's the metadata service of another cloud, which doesn't
+// know the path (404), or rejects the request (403, 405).
+func unavailable(err error) bool {
+	switch e := err.(type) {
+	case *url.Error:
+		return true
+	case *statusError:
+		switch e.code {
+		case http.StatusForbidden, http.StatusMethodNotAllowed:
+			return true
+		}
+	}
+	return err == errNotFound
+}
+
+// setAttributes sets option values from a flat map of metadata attributes.
+// Attributes are matched to option keys case-insensitively, with key parts
+// joined by "-" or "_". Attributes which don't match an option are ignored.
+func setAttributes(l *Loader, attrs map[string]interface{}, prefix string) {
+	lower := map[string]string{}
+	for name := range attrs {
+		lower[strings.ToLower(name)] = name
+	}
+
+	for _, key := range l.Keys() {
+		k := key
+		if prefix != "" {
+			k = append([]string{prefix}, key...)
+		}
+
+		for _, kf := range []KeyFunc{DashKey, UnderscoreKey} {
+			name, ok := lower[kf(k)]
+			if !ok {
+				continue
+			}
+			l.SetFrom(key, attrs[name], name)
+			break
+		}
+	}
+}
+
+// lookupPath looks up a value in a nested map by key path,
+// case-insensitively.
+func lookupPath(data map[string]interface{}, key []string) (interface{}, bool) {
+	var cur interface{} = data
+	for _, part := range key {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for k, v := range m {
+			if strings.EqualFold(k, part) {
+				cur = v
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return cur, true
+}
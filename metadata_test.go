@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/computeMetadata/v1/instance/attributes/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"myapp-server-addr": ":1111", "ssh-keys": "ignored"}`))
+	})
+	mux.HandleFunc("/openstack/latest/meta_data.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"uuid": "x", "meta": {"SERVER_ADDR": ":2222"}}`))
+	})
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte("token"))
+	})
+	mux.HandleFunc("/latest/user-data", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("server:\n  addr: \":3333\"\npackages: [ignored]\n"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	check := func(p Provider, expect string) {
+		var addr string
+		opts := []*Opt{
+			{Key: []string{"Server", "Addr"}, Value: &addr},
+		}
+		l := NewLoader(opts, p)
+		l.Load()
+		if errs := l.Errors(); errs != nil {
+			t.Errorf("%s: unexpected errors: %v", p, errs)
+		}
+		if addr != expect {
+			t.Errorf("%s: expected %q got %q", p, expect, addr)
+		}
+	}
+
+	check(GCE(MetadataOpts{BaseURL: srv.URL, Prefix: "myapp"}), ":1111")
+	check(OpenStack(MetadataOpts{BaseURL: srv.URL}), ":2222")
+	check(EC2(MetadataOpts{BaseURL: srv.URL}), ":3333")
+
+	// When the metadata service can't be reached, the providers do nothing.
+	closed := httptest.NewServer(mux)
+	closed.Close()
+	opts := MetadataOpts{BaseURL: closed.URL, Timeout: 100 * time.Millisecond}
+	check(GCE(opts), "")
+	check(OpenStack(opts), "")
+	check(EC2(opts), "")
+
+	// Nor when the metadata service belongs to another cloud.
+	for _, code := range []int{http.StatusNotFound, http.StatusForbidden, http.StatusMethodNotAllowed} {
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "other cloud", code)
+		}))
+		opts := MetadataOpts{BaseURL: other.URL}
+		check(GCE(opts), "")
+		check(OpenStack(opts), "")
+		check(EC2(opts), "")
+		other.Close()
+	}
+
+	// Other errors are returned.
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+	l := NewLoader(nil, GCE(MetadataOpts{BaseURL: broken.URL}))
+	l.Load()
+	if errs := l.Errors(); errs == nil {
+		t.Error("expected error for status 500")
+	}
+}
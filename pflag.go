@@ -1,23 +1,47 @@
 package cli
 
 import (
+	"fmt"
 	"github.com/spf13/pflag"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 // PFlags loads option values from a pflag.FlagSet.
 // The given KeyFunc is used to format the flag names:
 // DotKey will create flags like "server.address",
 // DashKey will create "server-address", etc.
+//
+// Boolean options may be given without a value, e.g. "--verbose",
+// and negated with a hidden "--no-" flag, e.g. "--no-verbose".
+// Integer options marked as counters (see Opt.Count) are incremented
+// by each occurrence of the flag, e.g. "-vvv" sets 3.
+//
+// Elements of lists of structs may be given by index, e.g.
+// "--backends.0.addr a --backends.1.addr b", or as a repeated flag
+// of key=value pairs, e.g. "--backends addr=a,weight=2 --backends addr=b".
 func PFlags(fs *pflag.FlagSet, opts []*Opt, kf KeyFunc) Provider {
 	pf := &pflags{
 		KeyFunc: kf,
 		FlagSet: fs,
 	}
+	hasLists := false
+	var bools []*pflagValue
 
 	for _, opt := range opts {
-		flag := &pflagValue{opt: opt}
 		k := pf.keyfunc(opt.Key)
-		fs.VarP(flag, k, opt.Short, opt.Synopsis)
+		flag := newPflagValue(opt, k)
+		f := fs.VarPF(flag, k, opt.Short, opt.Synopsis)
+
+		switch {
+		case flag.count:
+			f.NoOptDefVal = "+1"
+		case flag.kind == reflect.Bool:
+			f.NoOptDefVal = "true"
+			bools = append(bools, flag)
+		}
 
 		if opt.Deprecated != "" {
 			fs.MarkDeprecated(k, opt.Deprecated)
@@ -26,6 +50,33 @@ func PFlags(fs *pflag.FlagSet, opts []*Opt, kf KeyFunc) Provider {
 			fs.MarkHidden(k)
 		}
 		pf.flags = append(pf.flags, flag)
+		if len(opt.Fields) > 0 {
+			hasLists = true
+		}
+	}
+
+	// Negation flags are added after the option flags, and skipped if an
+	// option has the same name, e.g. a "no-cache" option next to a "cache"
+	// bool, since pflag panics on duplicate names.
+	for _, flag := range bools {
+		name := "no-" + flag.name
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		neg := fs.VarPF(&pflagNegation{flag}, name, "", "")
+		neg.NoOptDefVal = "true"
+		fs.MarkHidden(name)
+	}
+
+	// Flags for elements of lists of structs, e.g. "--backends.0.addr",
+	// can't be known in advance, so they're added when pflag looks them up.
+	if hasLists {
+		norm := fs.GetNormalizeFunc()
+		fs.SetNormalizeFunc(func(fs *pflag.FlagSet, name string) pflag.NormalizedName {
+			n := norm(fs, name)
+			pf.addElemFlag(fs, string(n))
+			return n
+		})
 	}
 	return pf
 }
@@ -34,6 +85,46 @@ type pflags struct {
 	KeyFunc
 	*pflag.FlagSet
 	flags []*pflagValue
+	elems []*pflagElem
+	// seen tracks the names of element flags which have been added.
+	seen map[string]bool
+}
+
+// maxFlagIndex limits the index of element flags, e.g. "--backends.0.addr",
+// so that a typo doesn't allocate a huge list.
+const maxFlagIndex = 10000
+
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// addElemFlag adds a hidden flag for the element field with the given name,
+// if any, e.g. "backends.0.addr".
+func (f *pflags) addElemFlag(fs *pflag.FlagSet, name string) {
+	if f.seen[name] {
+		return
+	}
+	for _, flag := range f.flags {
+		for _, d := range digits.FindAllString(name, -1) {
+			i, err := strconv.Atoi(d)
+			if err != nil || i > maxFlagIndex {
+				continue
+			}
+			for _, field := range flag.opt.Fields {
+				if f.keyfunc(elemKey(flag.opt.Key, i, field.Key)) != name {
+					continue
+				}
+				if f.seen == nil {
+					f.seen = map[string]bool{}
+				}
+				f.seen[name] = true
+
+				e := &pflagElem{list: flag, index: i, field: field, name: name}
+				ef := fs.VarPF(e, name, "", field.Synopsis)
+				ef.Hidden = true
+				f.elems = append(f.elems, e)
+				return
+			}
+		}
+	}
 }
 
 func (f *pflags) keyfunc(key []string) string {
@@ -45,30 +136,273 @@ func (f *pflags) keyfunc(key []string) string {
 
 func (f *pflags) Provide(l *Loader) error {
 	for _, flag := range f.flags {
+		if len(flag.opt.Fields) > 0 {
+			f.provideList(l, flag)
+			continue
+		}
 		if flag.set {
-			l.Set(flag.opt.Key, flag.val)
+			l.SetFrom(flag.opt.Key, flag.val, "--"+flag.name)
 		}
 	}
 	return nil
 }
 
+// provideList sets a list of structs from the repeated flag, if any,
+// and then the element flags, e.g. "--backends.0.addr".
+func (f *pflags) provideList(l *Loader, flag *pflagValue) {
+	var list []interface{}
+	var locs []string
+
+	if flag.set {
+		list = []interface{}{}
+		locs = append(locs, "--"+flag.name)
+		items, _ := flag.val.([]string)
+		for _, item := range items {
+			// Errors were already reported by Set.
+			elem, _ := parseElem(item)
+			list = append(list, elem)
+		}
+	}
+
+	for _, e := range f.elems {
+		if e.list != flag || !e.set {
+			continue
+		}
+		for len(list) <= e.index {
+			list = append(list, map[string]interface{}{})
+		}
+		setPath(list[e.index].(map[string]interface{}), e.field.Key, e.val)
+		locs = append(locs, "--"+e.name)
+	}
+
+	if list != nil {
+		l.SetFrom(flag.opt.Key, list, strings.Join(locs, ", "))
+	}
+}
+
+// parseElem parses an element of a list of structs from key=value pairs,
+// e.g. "addr=a,tls.cert=b".
+func parseElem(s string) (map[string]interface{}, error) {
+	elem := map[string]interface{}{}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		setPath(elem, strings.Split(kv[0], "."), kv[1])
+	}
+	return elem, nil
+}
+
+func (f *pflags) String() string {
+	return "flags"
+}
+
+// Static returns true: flags are not reloaded.
+func (f *pflags) Static() bool {
+	return true
+}
+
+// pflagValue implements pflag.Value for an option.
+//
+// Repeated flags for slice and map options accumulate values,
+// e.g. "--tags a=1 --tags b=2,c=3" sets three map entries, and
+// "--names a --names b,c" sets three list items. An empty value,
+// e.g. "--tags=", resets the option to an empty list or map,
+// which also overrides any value from config files, etc.
 type pflagValue struct {
-	opt *Opt
-	val interface{}
-	set bool
+	opt  *Opt
+	name string
+	kind reflect.Kind
+	val  interface{}
+	set  bool
+	// count is true for counter options, in which case n is the count.
+	count bool
+	n     int
+}
+
+func newPflagValue(opt *Opt, name string) *pflagValue {
+	p := &pflagValue{opt: opt, name: name, kind: collectionKind(opt)}
+
+	t := opt.valueType()
+	// Pointer options, e.g. *bool, are treated like the type they point to.
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if p.kind == reflect.Invalid && t != nil && !isUnmarshaler(reflect.PtrTo(t)) {
+		switch t.Kind() {
+		case reflect.Bool:
+			p.kind = reflect.Bool
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			p.count = opt.Count
+		}
+	}
+	return p
 }
 
 func (p *pflagValue) Set(v string) error {
-	p.val = v
+	if p.count {
+		if v == "+1" {
+			p.n++
+		} else {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			p.n = n
+		}
+		p.val = p.n
+		p.set = true
+		return nil
+	}
+
+	switch p.kind {
+	case reflect.Slice:
+		if len(p.opt.Fields) > 0 && v != "" {
+			if _, err := parseElem(v); err != nil {
+				return err
+			}
+		}
+		list, _ := p.val.([]string)
+		if v == "" || list == nil {
+			list = []string{}
+		}
+		switch {
+		case v == "":
+		case len(p.opt.Fields) > 0:
+			// Each value is one element, e.g. "addr=a,weight=2".
+			list = append(list, v)
+		default:
+			list = append(list, strings.Split(v, ",")...)
+		}
+		p.val = list
+
+	case reflect.Map:
+		m, _ := p.val.(map[string]string)
+		if v == "" || m == nil {
+			m = map[string]string{}
+		}
+		if v != "" {
+			for _, pair := range strings.Split(v, ",") {
+				kv := strings.SplitN(pair, "=", 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("expected key=value, got %q", pair)
+				}
+				m[kv[0]] = kv[1]
+			}
+		}
+		p.val = m
+
+	default:
+		p.val = v
+	}
 	p.set = true
 	return nil
 }
 
+// String returns the default value, or the accumulated value
+// once the flag has been set.
 func (p *pflagValue) String() string {
-	return p.opt.DefaultString
+	if !p.set {
+		return p.opt.DefaultString
+	}
+	s := fmt.Sprint(p.val)
+	if p.kind != reflect.Invalid {
+		s = collectionString(p.val)
+	}
+	if p.opt.Sensitive && s != "" {
+		return Redacted
+	}
+	return s
 }
 
 func (p *pflagValue) Type() string {
+	switch {
+	case p.count:
+		return "count"
+	case p.kind == reflect.Bool:
+		return "bool"
+	case p.opt.Unit != "":
+		return p.opt.Unit
+	}
 	// TODO shows io.Writer, which doesn't make much sense for CLI.
 	return p.opt.Type
 }
+
+// pflagElem implements the flag of a field of an element
+// in a list of structs, e.g. "--backends.0.addr".
+type pflagElem struct {
+	list  *pflagValue
+	index int
+	field *Opt
+	name  string
+	val   string
+	set   bool
+}
+
+func (e *pflagElem) Set(v string) error {
+	e.val = v
+	e.set = true
+	return nil
+}
+
+func (e *pflagElem) String() string {
+	return ""
+}
+
+func (e *pflagElem) Type() string {
+	return e.field.Type
+}
+
+// pflagNegation implements the "--no-" flag of a boolean option.
+type pflagNegation struct {
+	p *pflagValue
+}
+
+func (n *pflagNegation) Set(v string) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	return n.p.Set(strconv.FormatBool(!b))
+}
+
+func (n *pflagNegation) String() string {
+	return ""
+}
+
+func (n *pflagNegation) Type() string {
+	return "bool"
+}
+
+// collectionKind returns reflect.Slice or reflect.Map if the option
+// is a list or map whose flags should accumulate, otherwise reflect.Invalid.
+// Types which parse themselves, such as net.IP, are not collections.
+func collectionKind(opt *Opt) reflect.Kind {
+	t := opt.valueType()
+	if t == nil || isUnmarshaler(reflect.PtrTo(t)) {
+		return reflect.Invalid
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return reflect.Invalid
+		}
+		return reflect.Slice
+	case reflect.Map:
+		return reflect.Map
+	}
+	return reflect.Invalid
+}
+
+// collectionString formats a list or map in the style of pflag's
+// slice flags, e.g. "[a,b]" or "[a=1,b=2]". Empty values are formatted
+// as an empty string, so that they're not shown as a default in help.
+func collectionString(val interface{}) string {
+	v, ok := plainValue(reflect.ValueOf(val))
+	if !ok || !isCollection(v) {
+		return ""
+	}
+	return "[" + flatValue(v) + "]"
+}
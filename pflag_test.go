@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"github.com/spf13/pflag"
+	"reflect"
+	"testing"
+)
+
+func TestPFlagsRepeated(t *testing.T) {
+	opt := struct {
+		Tags  map[string]string
+		Names []string
+		Ports []int
+		Name  string
+	}{
+		Tags:  map[string]string{"env": "dev"},
+		Names: []string{"default"},
+		Ports: []int{80},
+	}
+
+	opts := []*Opt{
+		{Key: []string{"Tags"}, Value: &opt.Tags, DefaultValue: opt.Tags},
+		{Key: []string{"Names"}, Value: &opt.Names, DefaultValue: opt.Names},
+		{Key: []string{"Ports"}, Value: &opt.Ports, DefaultValue: opt.Ports},
+		{Key: []string{"Name"}, Value: &opt.Name},
+	}
+	Enrich(&Cmd{Opts: opts})
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	l := NewLoader(opts, PFlags(fs, opts, DotKey))
+
+	if s := fs.Lookup("tags").Value.String(); s != "[env=dev]" {
+		t.Errorf("unexpected default string %q", s)
+	}
+
+	err := fs.Parse([]string{
+		"--tags", "a=1", "--tags", "b=2,c=3",
+		"--names", "x", "--names", "y,z",
+		"--ports=",
+		"--name", "one", "--name", "two",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fs.Lookup("tags").Value.String(); s != "[a=1,b=2,c=3]" {
+		t.Errorf("unexpected accumulated string %q", s)
+	}
+
+	l.Load()
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+
+	expectTags := map[string]string{"a": "1", "b": "2", "c": "3"}
+	if !reflect.DeepEqual(opt.Tags, expectTags) {
+		t.Errorf("expected %v, got %v", expectTags, opt.Tags)
+	}
+	expectNames := []string{"x", "y", "z"}
+	if !reflect.DeepEqual(opt.Names, expectNames) {
+		t.Errorf("expected %v, got %v", expectNames, opt.Names)
+	}
+	if len(opt.Ports) != 0 {
+		t.Errorf("expected ports to be reset, got %v", opt.Ports)
+	}
+	if opt.Name != "two" {
+		t.Errorf("expected last value for scalar flag, got %q", opt.Name)
+	}
+
+	err = fs.Parse([]string{"--tags", "nope"})
+	if err == nil {
+		t.Error("expected error for invalid key=value pair")
+	}
+}
+
+func TestPFlagsBoolAndCount(t *testing.T) {
+	opt := struct {
+		Verbose int
+		Debug   bool
+		Color   bool
+		Quiet   bool
+	}{Color: true}
+
+	opts := []*Opt{
+		{Key: []string{"Verbose"}, Value: &opt.Verbose, Short: "v", Tag: `count:"true"`},
+		{Key: []string{"Debug"}, Value: &opt.Debug},
+		{Key: []string{"Color"}, Value: &opt.Color, DefaultValue: true},
+		{Key: []string{"Quiet"}, Value: &opt.Quiet},
+	}
+	Enrich(&Cmd{Opts: opts})
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	l := NewLoader(opts, PFlags(fs, opts, DotKey))
+
+	err := fs.Parse([]string{"-vvv", "--debug", "--no-color", "--quiet=false"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Load()
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+
+	if opt.Verbose != 3 {
+		t.Errorf("expected verbose 3, got %d", opt.Verbose)
+	}
+	if !opt.Debug {
+		t.Error("expected debug to be true")
+	}
+	if opt.Color {
+		t.Error("expected color to be false")
+	}
+	if opt.Quiet {
+		t.Error("expected quiet to be false")
+	}
+	if !fs.Lookup("no-color").Hidden {
+		t.Error("expected negation flag to be hidden")
+	}
+}
+
+func TestPFlagsNegationConflict(t *testing.T) {
+	// Both orders: the option named "no-cache" before and after "cache".
+	for _, reverse := range []bool{false, true} {
+		var cache bool
+		var noCache string
+		opts := []*Opt{
+			{Key: []string{"Cache"}, Value: &cache},
+			{Key: []string{"No", "Cache"}, Value: &noCache},
+		}
+		if reverse {
+			opts[0], opts[1] = opts[1], opts[0]
+		}
+
+		fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		l := NewLoader(opts, PFlags(fs, opts, DashKey))
+
+		err := fs.Parse([]string{"--cache", "--no-cache", "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Load()
+		if errs := l.Errors(); errs != nil {
+			t.Fatal(errs)
+		}
+		if !cache || noCache != "x" {
+			t.Errorf("unexpected values %v %q", cache, noCache)
+		}
+	}
+}
+
+func TestPFlagsStructList(t *testing.T) {
+	var backends []testBackend
+	opts := []*Opt{testBackendOpt(&backends)}
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	l := NewLoader(opts, PFlags(fs, opts, DotKey))
+
+	err := fs.Parse([]string{
+		"--backends", "addr=a:80,maxweight=2",
+		"--backends.1.addr", "b:80",
+		"--backends.1.tls.cert=b.pem",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Load()
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+
+	expect := []testBackend{{Addr: "a:80", MaxWeight: 2}, {Addr: "b:80"}}
+	expect[1].TLS.Cert = "b.pem"
+	if !reflect.DeepEqual(backends, expect) {
+		t.Errorf("expected %+v, got %+v", expect, backends)
+	}
+
+	if fs.Parse([]string{"--backends.0.nope", "x"}) == nil {
+		t.Error("expected error for unknown element field")
+	}
+	if fs.Parse([]string{"--backends", "addr"}) == nil {
+		t.Error("expected error for invalid element")
+	}
+}
@@ -0,0 +1,298 @@
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// StaticProvider is implemented by providers whose values can't change
+// after startup, such as flags and environment variables. During Reload,
+// the values originally loaded from a static provider are reused instead
+// of running the provider again, which keeps their precedence intact.
+type StaticProvider interface {
+	Provider
+	Static() bool
+}
+
+// Change describes an option value which was changed by Reload.
+type Change struct {
+	// Key is the key of the option which changed.
+	Key []string
+	// Old is the value before reloading.
+	Old interface{}
+	// New is the value after reloading.
+	New interface{}
+	// Source describes where the new value was loaded from.
+	Source *Source
+}
+
+// OnChange registers a function which is called after each Reload
+// which changes at least one option value.
+func (l *Loader) OnChange(f func([]Change)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onChange = append(l.onChange, f)
+}
+
+// Reload runs the providers again, and sets any option values which changed.
+// Values from static providers (see StaticProvider), such as flags and
+// environment variables, are not reloaded.
+//
+// Values are loaded into a copy of the options first; if there are
+// any errors, no values are changed and the errors are returned.
+// Otherwise, all changed values are swapped in together, and the
+// changes are returned and passed to any functions registered with OnChange.
+//
+// Note that command functions receive a copy of their options,
+// so long-running commands should use OnChange (or OnReload)
+// to find out about new values.
+func (l *Loader) Reload() ([]Change, error) {
+	l.mu.Lock()
+
+	// Load the values into a copy of the options.
+	var shadow []*Opt
+	for _, opt := range l.opts {
+		cp := *opt
+		cp.IsSet = false
+		cp.Source = nil
+		cp.Value = newValue(opt)
+		cp.Ref = nil
+		shadow = append(shadow, &cp)
+	}
+
+	sl := &Loader{
+		opts:      shadow,
+		keys:      l.keys,
+		providers: l.providers,
+		Strict:    l.Strict,
+		Coerce:    l.Coerce,
+	}
+
+	for _, src := range l.providers {
+		sl.current = src
+		if s, ok := src.(StaticProvider); ok && s.Static() {
+			// Replay the values originally loaded from this provider.
+			for _, opt := range l.opts {
+				if opt.Source != nil && opt.Source.Provider == src {
+					sl.SetFrom(opt.Key, opt.Source.Raw, opt.Source.Location)
+				}
+			}
+			continue
+		}
+
+		err := src.Provide(sl)
+		if err != nil {
+			sl.addError(err)
+		}
+	}
+	sl.current = nil
+
+	if errs := sl.Errors(); errs != nil {
+		l.mu.Unlock()
+		return nil, combineErrors(errs)
+	}
+
+	// Swap in the changed values.
+	var changes []Change
+	for i, opt := range l.opts {
+		next := shadow[i]
+		ref := opt.ref(false)
+		if ref == nil && !next.IsSet {
+			// The option has a nil parent, and is still unset.
+			continue
+		}
+		old := deref(ref)
+		val := deref(next.Value)
+		if reflect.DeepEqual(old, val) {
+			continue
+		}
+
+		reflect.ValueOf(opt.ref(true)).Elem().Set(reflect.ValueOf(next.Value).Elem())
+		opt.IsSet = next.IsSet
+		opt.Source = next.Source
+
+		changes = append(changes, Change{
+			Key:    opt.Key,
+			Old:    old,
+			New:    val,
+			Source: optSource(opt),
+		})
+	}
+
+	subs := append([]func([]Change){}, l.onChange...)
+	l.mu.Unlock()
+
+	if changes != nil {
+		for _, f := range subs {
+			f(changes)
+		}
+	}
+	return changes, nil
+}
+
+// newValue returns a pointer to a new value of the same type as the
+// option's value, initialized to the option's default value.
+func newValue(opt *Opt) interface{} {
+	t := reflect.TypeOf(opt.Value).Elem()
+	ptr := reflect.New(t)
+
+	def := reflect.ValueOf(opt.DefaultValue)
+	if def.IsValid() && def.Type().AssignableTo(t) {
+		ptr.Elem().Set(def)
+	}
+	return ptr.Interface()
+}
+
+// ReloadOnSignal calls Reload whenever one of the given signals is received.
+// If no signals are given, SIGHUP is used. Reload errors are passed to "onErr",
+// which may be nil. The returned function stops watching for signals.
+func (l *Loader) ReloadOnSignal(onErr func(error), sig ...os.Signal) (stop func()) {
+	// signal.Notify relays all signals if none are given,
+	// including SIGINT and SIGTERM, which must still stop the process.
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sig...)
+
+	go func() {
+		for {
+			select {
+			case <-ch:
+				l.reload(onErr)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// WatchFiles polls the config files of the loader's file providers
+// (e.g. YAML and Layered) at the given interval, and calls Reload
+// when a file is modified, created, or removed. Reload errors are
+// passed to "onErr", which may be nil. The returned function stops watching.
+func (l *Loader) WatchFiles(interval time.Duration, onErr func(error)) (stop func()) {
+	done := make(chan struct{})
+	last := l.fileStats()
+
+	go func() {
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+				cur := l.fileStats()
+				if !reflect.DeepEqual(cur, last) {
+					last = cur
+					l.reload(onErr)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+func (l *Loader) reload(onErr func(error)) {
+	_, err := l.Reload()
+	if err != nil && onErr != nil {
+		onErr(err)
+	}
+}
+
+// filesProvider is implemented by providers which load files,
+// so that the files can be watched for changes.
+type filesProvider interface {
+	files(l *Loader) []string
+	stat(path string) (os.FileInfo, error)
+}
+
+// fileStat is used to detect when a file has changed.
+type fileStat struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// fileStats returns the current state of the files
+// loaded by the loader's providers.
+func (l *Loader) fileStats() map[string]fileStat {
+	stats := map[string]fileStat{}
+	for _, p := range l.providers {
+		fp, ok := p.(filesProvider)
+		if !ok {
+			continue
+		}
+		for _, path := range fp.files(l) {
+			info, err := fp.stat(path)
+			if err != nil {
+				stats[path] = fileStat{}
+				continue
+			}
+			stats[path] = fileStat{true, info.Size(), info.ModTime()}
+		}
+	}
+	return stats
+}
+
+// running tracks the loader of the command currently being run by Run,
+// so that OnReload can find it.
+var running struct {
+	sync.Mutex
+	loader *Loader
+	stop   func()
+}
+
+// OnReload registers a function which is called when the options
+// of the currently running command are reloaded. This is useful
+// in long-running commands, such as servers, which are run by
+// Run (e.g. via AutoCobra) and don't have access to the Loader.
+//
+// The first call to OnReload enables reloading when the process
+// receives SIGHUP. OnReload does nothing if no command is running.
+func OnReload(f func([]Change)) {
+	running.Lock()
+	defer running.Unlock()
+
+	l := running.loader
+	if l == nil {
+		return
+	}
+	l.OnChange(f)
+
+	if running.stop == nil {
+		running.stop = l.ReloadOnSignal(nil, syscall.SIGHUP)
+	}
+}
+
+// setRunning sets the loader of the currently running command,
+// stopping signal handling for the previous one, if any.
+func setRunning(l *Loader) {
+	running.Lock()
+	defer running.Unlock()
+
+	if running.stop != nil {
+		running.stop()
+		running.stop = nil
+	}
+	running.loader = l
+}
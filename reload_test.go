@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cli-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(path, []byte("name: one\naddr: :8080\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("RELOAD_ADDR", ":9090")
+	defer os.Unsetenv("RELOAD_ADDR")
+
+	var name, addr string
+	opts := []*Opt{
+		{Key: []string{"name"}, Value: &name},
+		{Key: []string{"addr"}, Value: &addr},
+	}
+	l := NewLoader(opts,
+		Env("reload"),
+		YAML(FileOpts{Paths: []string{path}}),
+	)
+	l.Load()
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+
+	var notified []Change
+	l.OnChange(func(c []Change) {
+		notified = c
+	})
+
+	// Env. vars are static, so this change must be ignored.
+	os.Setenv("RELOAD_ADDR", ":7070")
+	err = ioutil.WriteFile(path, []byte("name: two\naddr: :8080\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := l.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "two" || addr != ":9090" {
+		t.Errorf("unexpected values %q %q", name, addr)
+	}
+	if len(changes) != 1 || changes[0].Old != "one" || changes[0].New != "two" {
+		t.Errorf("unexpected changes %#v", changes)
+	}
+	if len(notified) != 1 {
+		t.Errorf("expected OnChange to be called")
+	}
+
+	// Errors leave the values unchanged.
+	err = ioutil.WriteFile(path, []byte("name: three\nnam: typo\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.Reload()
+	if err == nil {
+		t.Error("expected error")
+	}
+	if name != "two" {
+		t.Errorf("expected value to be unchanged, got %q", name)
+	}
+}
+
+// countProvider sets "name" to the number of times it has provided values.
+type countProvider struct {
+	n int
+}
+
+func (c *countProvider) Provide(l *Loader) error {
+	c.n++
+	l.Set([]string{"name"}, fmt.Sprint(c.n))
+	return nil
+}
+
+// TestReloadConcurrentReads reads values while reloading,
+// which is checked by `go test -race`.
+func TestReloadConcurrentReads(t *testing.T) {
+	var name string
+	opts := []*Opt{
+		{Key: []string{"name"}, Value: &name},
+	}
+	l := NewLoader(opts, &countProvider{})
+	l.Load()
+
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 100; i++ {
+			if _, err := l.Reload(); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for i := 0; i < 100; i++ {
+		l.Get([]string{"name"})
+		l.Errors()
+		l.Sources()
+		l.Dump(ioutil.Discard, DumpOpts{})
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if v := l.GetString([]string{"name"}); v != "101" {
+		t.Errorf("expected name to be 101, got %q", v)
+	}
+}
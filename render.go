@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Render writes a value returned by a command function to "w"
+// in the given format:
+//
+//   text   the default. fmt.Stringer values are written using String,
+//          lists are written one item per line, and other values
+//          are written with fmt.
+//   json   indented JSON.
+//   yaml   YAML.
+//   table  a table with a column per field, for a struct or list of structs,
+//          or per key, for a map or list of maps.
+//
+// Nil values aren't written.
+func Render(w io.Writer, val interface{}, format string) error {
+	if err := checkRenderFormat(format); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(val)
+	if !v.IsValid() || isNilValue(v) {
+		return nil
+	}
+
+	switch format {
+	case "", "text":
+		return renderText(w, v)
+
+	case "json":
+		b, err := json.MarshalIndent(val, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+
+	case "yaml":
+		b, err := yaml.Marshal(val)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+
+	default:
+		return renderTable(w, v)
+	}
+}
+
+func checkRenderFormat(format string) error {
+	switch format {
+	case "", "text", "json", "yaml", "table":
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, expected one of: text, json, yaml, table", format)
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+func renderText(w io.Writer, v reflect.Value) error {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		_, err := fmt.Fprintln(w, s.String())
+		return err
+	}
+
+	switch {
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		_, err := w.Write(v.Bytes())
+		return err
+
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			el := v.Index(i)
+			if isNilValue(el) {
+				continue
+			}
+			if err := renderText(w, el); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case v.Kind() == reflect.Ptr:
+		return renderText(w, v.Elem())
+
+	case v.Kind() == reflect.Struct:
+		_, err := fmt.Fprintf(w, "%+v\n", v.Interface())
+		return err
+	}
+
+	_, err := fmt.Fprintln(w, v.Interface())
+	return err
+}
+
+// renderTable writes a struct, map, or list of structs or maps, as a table.
+// Lists of other values are written as a single "VALUE" column.
+func renderTable(w io.Writer, v reflect.Value) error {
+	rows := []reflect.Value{v}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		rows = nil
+		for i := 0; i < v.Len(); i++ {
+			rows = append(rows, v.Index(i))
+		}
+	}
+
+	var header []string
+	var cells [][]string
+	for _, row := range rows {
+		for row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface {
+			row = row.Elem()
+		}
+		if !row.IsValid() {
+			continue
+		}
+
+		switch row.Kind() {
+		case reflect.Struct:
+			if header == nil {
+				for i := 0; i < row.NumField(); i++ {
+					if f := row.Type().Field(i); f.PkgPath == "" {
+						header = append(header, f.Name)
+					}
+				}
+			}
+			var line []string
+			for _, name := range header {
+				line = append(line, tableCell(row.FieldByName(name)))
+			}
+			cells = append(cells, line)
+
+		case reflect.Map:
+			if header == nil {
+				for _, k := range row.MapKeys() {
+					header = append(header, fmt.Sprint(k.Interface()))
+				}
+				sort.Strings(header)
+			}
+			byName := map[string]reflect.Value{}
+			for _, k := range row.MapKeys() {
+				byName[fmt.Sprint(k.Interface())] = row.MapIndex(k)
+			}
+			var line []string
+			for _, name := range header {
+				line = append(line, tableCell(byName[name]))
+			}
+			cells = append(cells, line)
+
+		default:
+			header = []string{"value"}
+			cells = append(cells, []string{tableCell(row)})
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for i, h := range header {
+		header[i] = strings.ToUpper(h)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, line := range cells {
+		fmt.Fprintln(tw, strings.Join(line, "\t"))
+	}
+	return tw.Flush()
+}
+
+func tableCell(v reflect.Value) string {
+	if !v.IsValid() || isNilValue(v) {
+		return ""
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if v.Kind() == reflect.Ptr {
+		return tableCell(v.Elem())
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+type testTask struct {
+	ID          int
+	Description string
+	done        bool
+}
+
+func ExampleRender() {
+	tasks := []testTask{
+		{ID: 1, Description: "write docs"},
+		{ID: 2, Description: "fix bugs"},
+	}
+	Render(os.Stdout, tasks, "table")
+	// Output:
+	// ID  DESCRIPTION
+	// 1   write docs
+	// 2   fix bugs
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		val    interface{}
+		format string
+		expect string
+	}{
+		{nil, "text", ""},
+		{(*testTask)(nil), "json", ""},
+		{"hello", "text", "hello\n"},
+		{[]string{"a", "b"}, "", "a\nb\n"},
+		{testURL("http://example.com"), "text", "http://example.com\n"},
+		{&testTask{ID: 1}, "text", "{ID:1 Description: done:false}\n"},
+		{testTask{ID: 1, Description: "x"}, "json", "{\n  \"ID\": 1,\n  \"Description\": \"x\"\n}\n"},
+		{map[string]int{"b": 2, "a": 1}, "yaml", "a: 1\nb: 2\n"},
+		{[]map[string]string{{"name": "a", "env": "dev"}}, "table", "ENV  NAME\ndev  a\n"},
+		{[]int{1, 2}, "table", "VALUE\n1\n2\n"},
+	}
+
+	for _, test := range tests {
+		b := &bytes.Buffer{}
+		err := Render(b, test.val, test.format)
+		if err != nil {
+			t.Errorf("rendering %#v as %q: %v", test.val, test.format, err)
+			continue
+		}
+		if b.String() != test.expect {
+			t.Errorf("rendering %#v as %q: expected %q, got %q", test.val, test.format, test.expect, b.String())
+		}
+	}
+
+	if Render(&bytes.Buffer{}, "x", "xml") == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+type testURL string
+
+func (u testURL) String() string {
+	return string(u)
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// SignalOpts describes how a running command is stopped by signals.
+type SignalOpts struct {
+	// Signals cancel the context of the running command,
+	// e.g. os.Interrupt. If empty, signals aren't handled.
+	Signals []os.Signal
+	// GracePeriod is how long the command has to return after its
+	// context is canceled, before the process is forced to exit.
+	// Zero waits until the command returns.
+	GracePeriod time.Duration
+	// Exit is called to force the process to exit, when a second signal
+	// is received or the grace period ends. Defaults to os.Exit.
+	Exit func(code int)
+}
+
+// DefaultSignalOpts is used by Run, and so by AutoCobra: SIGINT and SIGTERM
+// cancel the context of the running command, if it takes one (see
+// Cmd.HasContext), and the process exits if the command doesn't return
+// within 10 seconds, or if a second signal is received.
+var DefaultSignalOpts = SignalOpts{
+	Signals:     []os.Signal{os.Interrupt, syscall.SIGTERM},
+	GracePeriod: 10 * time.Second,
+}
+
+// SignalContext returns a copy of ctx which is canceled when one of the
+// signals described by "s" is received. After that, a second signal, or the
+// end of the grace period, forces the process to exit with the conventional
+// code of 128 plus the signal number, e.g. 130 for SIGINT.
+//
+// The returned function stops handling signals and cancels the context.
+// Call it when the command returns.
+func SignalContext(ctx context.Context, s SignalOpts) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if len(s.Signals) == 0 {
+		return ctx, cancel
+	}
+
+	exit := s.Exit
+	if exit == nil {
+		exit = os.Exit
+	}
+
+	ch := make(chan os.Signal, 2)
+	done := make(chan struct{})
+	signal.Notify(ch, s.Signals...)
+
+	go func() {
+		var sig os.Signal
+		select {
+		case sig = <-ch:
+			cancel()
+		case <-done:
+			return
+		}
+
+		code := 1
+		if n, ok := sig.(syscall.Signal); ok {
+			code = 128 + int(n)
+		}
+
+		var timeout <-chan time.Time
+		if s.GracePeriod > 0 {
+			t := time.NewTimer(s.GracePeriod)
+			defer t.Stop()
+			timeout = t.C
+		}
+
+		select {
+		case <-ch:
+			exit(code)
+		case <-timeout:
+			exit(code)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+			cancel()
+		})
+	}
+}
@@ -0,0 +1,121 @@
+//go:build !windows
+// +build !windows
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalContext(t *testing.T) {
+	exited := make(chan int, 1)
+	s := SignalOpts{
+		Signals: []os.Signal{syscall.SIGUSR1},
+		Exit:    func(code int) { exited <- code },
+	}
+	ctx, stop := SignalContext(context.Background(), s)
+	defer stop()
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected context to be canceled by the first signal")
+	}
+
+	select {
+	case code := <-exited:
+		t.Fatalf("unexpected exit %d after the first signal", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	select {
+	case code := <-exited:
+		if code != 128+int(syscall.SIGUSR1) {
+			t.Errorf("unexpected exit code %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected exit after the second signal")
+	}
+}
+
+func TestSignalGracePeriod(t *testing.T) {
+	exited := make(chan int, 1)
+	s := SignalOpts{
+		Signals:     []os.Signal{syscall.SIGUSR2},
+		GracePeriod: 10 * time.Millisecond,
+		Exit:        func(code int) { exited <- code },
+	}
+	_, stop := SignalContext(context.Background(), s)
+	defer stop()
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("expected exit after the grace period")
+	}
+}
+
+// errProvider fails every time it provides values.
+type errProvider struct{}
+
+func (errProvider) Provide(l *Loader) error {
+	return errors.New("provided")
+}
+
+func TestReloadOnSignalDefault(t *testing.T) {
+	errs := make(chan error, 1)
+	l := NewLoader(nil, errProvider{})
+	stop := l.ReloadOnSignal(func(err error) { errs <- err })
+	defer stop()
+
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("expected SIGHUP to reload by default")
+	}
+}
+
+// ctxSpec records the context it's run with.
+type ctxSpec struct {
+	cmd Cmd
+	ctx context.Context
+}
+
+func (c *ctxSpec) Cmd() *Cmd { return &c.cmd }
+func (c *ctxSpec) Run(ctx context.Context) (interface{}, error) {
+	c.ctx = ctx
+	return nil, nil
+}
+
+func TestRunSignals(t *testing.T) {
+	// Without a context, signals keep their default behavior,
+	// so the command gets a context which is never canceled.
+	spec := &ctxSpec{}
+	err := Run(spec, NewLoader(nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.ctx.Done() != nil {
+		t.Error("expected no signal handling for a command without a context")
+	}
+
+	spec = &ctxSpec{cmd: Cmd{HasContext: true}}
+	err = Run(spec, NewLoader(nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-spec.ctx.Done():
+	default:
+		t.Error("expected the context to be canceled after the command returns")
+	}
+}
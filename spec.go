@@ -1,9 +1,19 @@
 package cli
 
+import (
+	"context"
+)
+
 // Spec is implemented by the generated by the `cli` code generator.
 type Spec interface {
 	Cmd() *Cmd
-	Run()
+	// Run runs the command. The context is passed to command functions
+	// which take a context.Context as their first argument, and is
+	// canceled when the command should stop, e.g. on SIGINT.
+	//
+	// Run returns the value and error returned by the command function,
+	// if any. See Cmd.Returns and Render.
+	Run(ctx context.Context) (interface{}, error)
 }
 
 // Cmd holds metadata related to a CLI command.
@@ -19,6 +29,13 @@ type Cmd struct {
 	// Opts describes metadata about the function options
 	// (the `opt` argument, by convention).
 	Opts []*Opt
+	// Returns describes the type of the value returned by the function,
+	// e.g. "[]Task", or is empty if the function doesn't return a value,
+	// other than an error.
+	Returns string
+	// HasContext is true if the function takes a context.Context
+	// as its first argument. Run only handles signals for these commands.
+	HasContext bool
 
 	// The fields below are normally set by Enrich.
 
@@ -71,9 +88,33 @@ type Opt struct {
 	Type string
 	// Short is the name of the short version of a flag for this option.
 	Short string
+	// Tag is the raw struct tag of this field, e.g. `sensitive:"true"`.
+	Tag string
+	// Sensitive marks this option as containing a secret, such as a password.
+	// The value is redacted wherever it is shown, e.g. in help, dumps, and errors.
+	Sensitive bool
+	// Rules contains validation rules for this option, see Rule.
+	Rules []Rule
+	// Count marks an integer option as a counter, e.g. for verbosity,
+	// where each occurrence of the flag increments the value, e.g. "-vvv".
+	Count bool
+	// Unit is the unit of a numeric option, e.g. "bytes",
+	// which allows values such as "10MB". See UnitBytes.
+	Unit string
+	// Fields describes the fields of each element of a list of structs,
+	// e.g. for an option of type []Backend. Field keys are relative to
+	// the element, e.g. ["Addr"], and field Values are nil.
+	Fields []*Opt
 	// Value contains a pointer to the value for this option.
 	// Used by Loader machinery to set the value of this option.
+	// If Ref is set, Value is a typed nil pointer, e.g. (*string)(nil),
+	// which describes the type of the option.
 	Value interface{}
+	// Ref returns a pointer to the value of this option, for options
+	// with a pointer parent, e.g. Cert in `TLS *TLSOpts`. If "alloc" is true,
+	// nil parents are allocated, otherwise Ref returns nil if a parent is nil.
+	// Generated by the code generator; nil for other options.
+	Ref func(alloc bool) interface{}
 	// DefaultValue contains the default value of this option.
 	DefaultValue interface{}
 	// DefaultString contains a more human-friendly description
@@ -83,6 +124,9 @@ type Opt struct {
 	// Used by Loader machinery.
 	// TODO ugly.
 	IsSet bool
+	// Source describes where the value of this option was loaded from.
+	// Used by Loader machinery. Nil if the value has not been set.
+	Source *Source
 }
 
 // Arg defines a positional argument of a Cmd.
@@ -98,6 +142,10 @@ type Arg struct {
 	// e.g. func example(a string, b ...string)
 	// "b" is variadic.
 	Variadic bool
+	// Rules contains validation rules for this argument, see Rule.
+	// Parsed from a "Validate <name>: " annotation in the command doc,
+	// e.g. "Validate src: file". Rules are also used by shell completion.
+	Rules []Rule
 	// Value contains a pointer to the value for this argument.
 	// Used by `cli` machinery to set the value of this argument.
 	Value interface{}
@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Units which can be given to numeric options, via a `unit` struct tag
+// or a "Unit:" doc annotation:
+//
+//   MaxLogSize int64 `unit:"bytes"`
+//
+//   // Rate limit for requests.
+//   // Unit: rate
+//   RateLimit float64
+//
+// Values with a unit are parsed from strings, e.g. from a YAML file,
+// environment variable, or flag, and are formatted in the same unit
+// in help text and config dumps. Plain numbers are always accepted.
+const (
+	// UnitBytes parses byte sizes with SI or IEC suffixes,
+	// e.g. "10MB" (10,000,000) or "1.5GiB" (1,610,612,736).
+	UnitBytes = "bytes"
+	// UnitPercent parses percentages into percentage points,
+	// e.g. "50%" is 50.
+	UnitPercent = "percent"
+	// UnitRate parses rates into events per second,
+	// e.g. "100/s" is 100, "30/m" is 0.5, and "3600/h" is 1.
+	UnitRate = "rate"
+)
+
+type byteUnit struct {
+	suffix string
+	size   float64
+}
+
+// byteUnits is sorted by size, largest first.
+var byteUnits = []byteUnit{
+	{"PiB", 1 << 50},
+	{"PB", 1e15},
+	{"TiB", 1 << 40},
+	{"TB", 1e12},
+	{"GiB", 1 << 30},
+	{"GB", 1e9},
+	{"MiB", 1 << 20},
+	{"MB", 1e6},
+	{"KiB", 1 << 10},
+	{"KB", 1e3},
+}
+
+var rateUnits = map[string]float64{
+	"s": 1,
+	"m": 60,
+	"h": 3600,
+}
+
+// parseUnit parses a string with the given unit into a number.
+func parseUnit(unit, s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return n, nil
+	}
+
+	switch unit {
+	case UnitBytes:
+		num, suffix := splitNumber(s)
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid byte size %q", s)
+		}
+		suffix = strings.ToLower(strings.TrimSpace(suffix))
+		switch suffix {
+		case "b":
+			return n, nil
+		case "k", "m", "g", "t", "p":
+			suffix += "b"
+		case "ki", "mi", "gi", "ti", "pi":
+			suffix += "b"
+		}
+		for _, u := range byteUnits {
+			if strings.ToLower(u.suffix) == suffix {
+				return n * u.size, nil
+			}
+		}
+		return 0, fmt.Errorf("invalid byte size %q, unknown unit %q", s, suffix)
+
+	case UnitPercent:
+		if !strings.HasSuffix(s, "%") {
+			return 0, fmt.Errorf("invalid percentage %q", s)
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid percentage %q", s)
+		}
+		return n, nil
+
+	case UnitRate:
+		parts := strings.SplitN(s, "/", 2)
+		if len(parts) != 2 {
+			return 0, fmt.Errorf("invalid rate %q, expected e.g. 100/s", s)
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid rate %q", s)
+		}
+		per, ok := rateUnits[strings.TrimSpace(parts[1])]
+		if !ok {
+			return 0, fmt.Errorf("invalid rate %q, unknown unit %q", s, parts[1])
+		}
+		return n / per, nil
+	}
+	return 0, fmt.Errorf("unknown unit %q", unit)
+}
+
+// splitNumber splits a string such as "1.5GiB" into "1.5" and "GiB".
+func splitNumber(s string) (string, string) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.' || r == '-' || r == '+')
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// formatUnit formats a number in the given unit, e.g. "10MB",
+// so that it can be parsed again by parseUnit.
+func formatUnit(unit string, val interface{}) string {
+	n, ok := unitNumber(val)
+	if !ok {
+		return fmt.Sprint(val)
+	}
+
+	switch unit {
+	case UnitBytes:
+		// Use the largest unit which gives a short, exact number.
+		for _, u := range byteUnits {
+			if math.Abs(n) >= u.size && isWhole(n*100/u.size) {
+				return formatFloat(n/u.size) + u.suffix
+			}
+		}
+		return formatFloat(n) + "B"
+
+	case UnitPercent:
+		return formatFloat(n) + "%"
+
+	case UnitRate:
+		if n == 0 || math.Abs(n) >= 1 || !isWhole(n*60) && !isWhole(n*3600) {
+			return formatFloat(n) + "/s"
+		}
+		if isWhole(n * 60) {
+			return formatFloat(n*60) + "/m"
+		}
+		return formatFloat(n*3600) + "/h"
+	}
+	return fmt.Sprint(val)
+}
+
+// unitNumber returns the value of a number, of any kind, as a float.
+func unitNumber(val interface{}) (float64, bool) {
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func isWhole(f float64) bool {
+	return math.Abs(f-math.Round(f)) < 1e-9
+}
+
+func formatFloat(f float64) string {
+	if isWhole(f) {
+		f = math.Round(f)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// unitValue parses a raw option value which has a unit. Strings are
+// parsed into numbers; other values are returned unchanged. Fractional
+// values are an error for integer options, e.g. "1.5B".
+func unitValue(opt *Opt, val interface{}) (interface{}, error) {
+	s, ok := val.(string)
+	if !ok {
+		return val, nil
+	}
+	n, err := parseUnit(opt.Unit, s)
+	if err != nil {
+		return nil, err
+	}
+
+	t := opt.valueType()
+	if t == nil {
+		return nil, fmt.Errorf("unknown option type")
+	}
+	// Pointer options, e.g. *int64, are parsed by the type they point to.
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return n, nil
+	}
+	if !isWhole(n) {
+		return nil, fmt.Errorf("%q is not a whole number (%v)", s, n)
+	}
+	if n < math.MinInt64 || n > math.MaxInt64 {
+		return nil, fmt.Errorf("%q is out of range", s)
+	}
+	return int64(math.Round(n)), nil
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestParseUnit(t *testing.T) {
+	tests := []struct {
+		unit, in string
+		expect   float64
+	}{
+		{UnitBytes, "1024", 1024},
+		{UnitBytes, "10MB", 10e6},
+		{UnitBytes, "10mb", 10e6},
+		{UnitBytes, "1.5GiB", 1.5 * (1 << 30)},
+		{UnitBytes, "20 KB", 20e3},
+		{UnitBytes, "2k", 2e3},
+		{UnitBytes, "512B", 512},
+		{UnitPercent, "50%", 50},
+		{UnitPercent, "12.5 %", 12.5},
+		{UnitRate, "100/s", 100},
+		{UnitRate, "30/m", 0.5},
+		{UnitRate, "3600/h", 1},
+	}
+	for _, test := range tests {
+		got, err := parseUnit(test.unit, test.in)
+		if err != nil {
+			t.Errorf("parsing %q: %v", test.in, err)
+			continue
+		}
+		if got != test.expect {
+			t.Errorf("parsing %q: expected %v, got %v", test.in, test.expect, got)
+		}
+	}
+
+	for _, bad := range []string{"10XB", "MB", "1.2.3GB"} {
+		if _, err := parseUnit(UnitBytes, bad); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestFormatUnit(t *testing.T) {
+	tests := []struct {
+		unit   string
+		in     interface{}
+		expect string
+	}{
+		{UnitBytes, 0, "0B"},
+		{UnitBytes, 512, "512B"},
+		{UnitBytes, int64(10e6), "10MB"},
+		{UnitBytes, 1536, "1.5KiB"},
+		{UnitBytes, uint64(1 << 30), "1GiB"},
+		{UnitBytes, 1610612736, "1.5GiB"},
+		{UnitPercent, 50, "50%"},
+		{UnitRate, 100.0, "100/s"},
+		{UnitRate, 0.5, "30/m"},
+	}
+	for _, test := range tests {
+		got := formatUnit(test.unit, test.in)
+		if got != test.expect {
+			t.Errorf("formatting %v: expected %q, got %q", test.in, test.expect, got)
+		}
+	}
+}
+
+func ExampleUnitBytes() {
+	opt := struct {
+		MaxLogSize int64
+		Rate       float64
+	}{MaxLogSize: 10000}
+
+	opts := []*Opt{
+		{Key: []string{"MaxLogSize"}, Value: &opt.MaxLogSize, DefaultValue: opt.MaxLogSize, Tag: `unit:"bytes"`},
+		{Key: []string{"Rate"}, Value: &opt.Rate, RawDoc: "Request rate.\nUnit: rate"},
+	}
+	Enrich(&Cmd{Opts: opts})
+	fmt.Println("default:", opts[0].DefaultString)
+
+	os.Setenv("APP_MAXLOGSIZE", "1.5MiB")
+	os.Setenv("APP_RATE", "6000/m")
+	defer os.Unsetenv("APP_MAXLOGSIZE")
+	defer os.Unsetenv("APP_RATE")
+
+	l := NewLoader(opts, Env("app"))
+	l.Load()
+	fmt.Println(opt.MaxLogSize, opt.Rate)
+
+	Dump(os.Stdout, opts, DumpOpts{OmitDocs: true})
+
+	// Output:
+	// default: 10KB
+	// 1572864 100
+	// maxlogsize: 1.5MiB
+	// rate: 100/s
+}
+
+func TestUnitErrors(t *testing.T) {
+	var size int
+	opts := []*Opt{
+		{Key: []string{"Size"}, Value: &size, Unit: UnitBytes},
+	}
+	l := NewLoader(opts)
+	l.Set([]string{"Size"}, "1.5B")
+	if len(l.Errors()) != 1 {
+		t.Errorf("expected an error for a fractional byte size, got %v", l.Errors())
+	}
+}
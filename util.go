@@ -1,27 +1,111 @@
 package cli
 
 import (
+	"io/fs"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"unicode"
 )
 
-func exists(path string) bool {
-	_, err := os.Stat(path)
+func exists(fsys fs.FS, path string) bool {
+	_, err := statFile(fsys, path)
 	return !os.IsNotExist(err)
 }
 
+// deref returns the value an option's Value points to.
+func deref(ptr interface{}) interface{} {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+	return v.Elem().Interface()
+}
+
 // walk through a nested map, setting option values for the leaves.
-func flatten2(in map[string]interface{}, l *Loader, prefix []string) {
+// The value of a map option, e.g. map[string]string, is a leaf.
+// "loc" is used to describe the location of each leaf, and may be nil.
+func flatten2(in map[string]interface{}, l *Loader, prefix []string, loc func([]string) string) {
 	for k, v := range in {
-		path := append(prefix[:], k)
+		path := append(prefix[:len(prefix):len(prefix)], k)
+
+		if x, ok := v.(map[string]interface{}); ok && !l.isMap(path) {
+			flatten2(x, l, path, loc)
+			continue
+		}
+		where := ""
+		if loc != nil {
+			where = loc(path)
+		}
+		l.SetFrom(path, v, where)
+	}
+}
+
+// isMap returns true if the option at the given key is a map,
+// e.g. map[string]string.
+func (l *Loader) isMap(key []string) bool {
+	for _, opt := range l.opts {
+		if l.eq(key, opt.Key) {
+			return collectionKind(opt) == reflect.Map
+		}
+	}
+	return false
+}
+
+// lineOf makes a best-effort guess at the line number (starting at 1)
+// where the given key is defined in a YAML, JSON, or TOML document.
+// lineOf returns 0 if the key can't be found.
+func lineOf(b []byte, key []string) int {
+	lines := strings.Split(string(b), "\n")
+	part := 0
 
-		switch x := v.(type) {
-		case map[string]interface{}:
-			flatten2(x, l, path)
-		default:
-			l.Set(path, v)
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+
+		// TOML table headers, e.g. [server.tls]
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.Trim(line, "[]")
+			parts := strings.Split(name, ".")
+			if part+len(parts) < len(key) && keyPrefixEq(key[part:], parts) {
+				part += len(parts)
+			}
+			continue
+		}
+
+		if definesKey(line, key[part]) {
+			part++
+			if part == len(key) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// definesKey returns true if the line looks like it defines the given key,
+// e.g. `key: value`, `"key": value`, or `key = value`.
+func definesKey(line, key string) bool {
+	line = strings.TrimPrefix(line, "- ")
+	line = strings.TrimLeft(line, `"'`)
+	if len(line) < len(key) || !strings.EqualFold(line[:len(key)], key) {
+		return false
+	}
+	rest := strings.TrimLeft(line[len(key):], `"' `)
+	return strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "=")
+}
+
+// keyPrefixEq returns true if "prefix" is a case-insensitive prefix of "key".
+func keyPrefixEq(key, prefix []string) bool {
+	if len(prefix) > len(key) {
+		return false
+	}
+	for i := range prefix {
+		if !strings.EqualFold(strings.TrimSpace(key[i]), strings.Trim(strings.TrimSpace(prefix[i]), `"`)) {
+			return false
 		}
 	}
+	return true
 }
 
 // splitIdent splits a Go identifier, such as a function name,
@@ -54,3 +138,86 @@ func splitIdent(s string) []string {
 
 	return parts
 }
+
+// suggest returns the candidate which is most similar to "name",
+// or an empty string if none of the candidates are similar enough.
+func suggest(name string, candidates []string) string {
+	best := ""
+	// Allow roughly one edit per three characters.
+	min := len(name)/3 + 1
+
+	for _, c := range candidates {
+		d := editDistance(strings.ToLower(name), strings.ToLower(c))
+		if d < min {
+			min = d
+			best = c
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(x int, rest ...int) int {
+	for _, y := range rest {
+		if y < x {
+			x = y
+		}
+	}
+	return x
+}
+
+// setPath sets a value in a tree of maps, creating maps as needed,
+// e.g. setPath(m, ["tls", "cert"], v) sets m["tls"]["cert"] = v.
+func setPath(m map[string]interface{}, key []string, v interface{}) {
+	for _, k := range key[:len(key)-1] {
+		sub, ok := m[k].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			m[k] = sub
+		}
+		m = sub
+	}
+	m[key[len(key)-1]] = v
+}
+
+// walkPaths calls "fn" for each leaf value in a tree of maps,
+// in sorted key order.
+func walkPaths(m map[string]interface{}, prefix []string, fn func(key []string, v interface{})) {
+	for _, k := range sortedKeys(m) {
+		key := append(prefix[:len(prefix):len(prefix)], k)
+		if sub, ok := m[k].(map[string]interface{}); ok {
+			walkPaths(sub, key, fn)
+			continue
+		}
+		fn(key, m[k])
+	}
+}
+
+// elemKey returns the key of a field of an element in a list of structs,
+// e.g. ["Backends", "0", "Addr"].
+func elemKey(list []string, i int, field []string) []string {
+	key := append(list[:len(list):len(list)], strconv.Itoa(i))
+	return append(key, field...)
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"testing"
+)
+
+func TestLineOf(t *testing.T) {
+	check := func(doc string, expect int, key ...string) {
+		if got := lineOf([]byte(doc), key); got != expect {
+			t.Errorf("key %v: expected line %d got %d", key, expect, got)
+		}
+	}
+
+	yaml := `name: foo
+server:
+  # comment
+  addr: ":8080"
+  tls:
+    cert: path
+`
+	check(yaml, 1, "name")
+	check(yaml, 4, "server", "addr")
+	check(yaml, 6, "Server", "TLS", "Cert")
+	check(yaml, 0, "server", "missing")
+
+	json := `{
+  "server": {
+    "addr": ":8080"
+  }
+}`
+	check(json, 3, "server", "addr")
+
+	toml := `name = "foo"
+
+[server.tls]
+cert = "path"
+`
+	check(toml, 4, "server", "tls", "cert")
+}
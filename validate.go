@@ -0,0 +1,512 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule is a validation constraint on an option value. Rules are parsed
+// from a `validate` struct tag or a "Validate:" doc annotation,
+// as a comma-separated list:
+//
+//   Port int `validate:"required,min=1,max=65535"`
+//
+//   // Log level.
+//   // Validate: oneof=debug info error
+//   Level string
+//
+// Built-in rules:
+//
+//   required      the value must not be the zero value.
+//   min=N, max=N  numbers must be within the range. For strings,
+//                 slices, and maps, the length must be within the range.
+//   oneof=A B C   the value must be one of the space-separated values.
+//   regexp=RE     strings must match the regular expression. Since the
+//                 expression may contain commas, this must be the last rule.
+//   file          the value must be the path of an existing file.
+//   dir           the value must be the path of an existing directory.
+//   port          the value must be a valid port number (1-65535).
+//
+// Options which weren't set by a provider, e.g. which have their default
+// value, are only checked if they have a "required" rule, so that optional
+// options don't need a value. Empty values, such as "" or an empty list,
+// are only checked by "required". Numbers which were set are always checked
+// by "min", "max", and "port", including zero.
+type Rule struct {
+	Name string
+	Arg  string
+}
+
+// parseRules parses a comma-separated list of rules,
+// e.g. "required,min=1,max=10".
+func parseRules(s string) []Rule {
+	var rules []Rule
+	for s != "" {
+		var part string
+		if strings.HasPrefix(strings.TrimSpace(s), "regexp=") {
+			part, s = s, ""
+		} else if i := strings.Index(s, ","); i >= 0 {
+			part, s = s[:i], s[i+1:]
+		} else {
+			part, s = s, ""
+		}
+
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		r := Rule{Name: kv[0]}
+		if len(kv) == 2 {
+			r.Arg = kv[1]
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// Validator is implemented by option types which validate themselves.
+// Validate calls the Validate method of the options struct, and any
+// nested struct, which implements Validator.
+type Validator interface {
+	Validate() error
+}
+
+// OptSpec is implemented by specs which have an options struct.
+// The generated code implements this, so that Validate can find
+// Validate methods on the options struct.
+type OptSpec interface {
+	Spec
+	// Opt returns a pointer to the options struct.
+	Opt() interface{}
+}
+
+// ValidationError describes an option value which failed validation.
+type ValidationError struct {
+	// Key is the key of the option, or the path to the struct
+	// whose Validate method failed. Key is empty for the root options struct.
+	Key []string
+	// Source describes where the invalid value was loaded from, if known.
+	Source *Source
+	// Flag is the flag which sets the option, e.g. "--server.port", if known.
+	// Run sets Flag when the loader has a flags provider (see PFlags).
+	Flag string
+	Err  error
+}
+
+// Error returns an error message which names where the invalid value was
+// loaded from, e.g. "invalid server.port (env APP_SERVER_PORT): must be
+// at most 65535", or else the flag which sets the option, e.g.
+// "invalid --server.port: a value is required".
+func (v *ValidationError) Error() string {
+	name := "options"
+	if len(v.Key) > 0 {
+		name = DotKey(v.Key)
+	}
+	if v.Source != nil && v.Source.Provider != nil {
+		from := providerName(v.Source.Provider)
+		if v.Source.Location != "" {
+			from += " " + v.Source.Location
+		}
+		name += " (" + from + ")"
+	} else if v.Flag != "" {
+		name = v.Flag
+	}
+	return fmt.Sprintf("invalid %s: %s", name, v.Err)
+}
+
+// ValidationErrors is a list of validation errors,
+// returned by Validate so that all failures are reported together.
+type ValidationErrors []*ValidationError
+
+// ExitCode returns ExitConfig.
+func (v ValidationErrors) ExitCode() int {
+	return ExitConfig
+}
+
+func (v ValidationErrors) Error() string {
+	var lines []string
+	for _, err := range v {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the option values of the spec against each option's rules,
+// and calls the Validate method of the options struct, and any nested struct,
+// which implements Validator. All failures are returned together as
+// ValidationErrors, or nil if the options are valid.
+//
+// Rules on the fields of lists of structs (see Opt.Fields) are checked
+// for each element. Rules on positional arguments (see Arg.Rules) are
+// also checked.
+//
+// Run calls Validate after loading option values.
+func Validate(spec Spec) error {
+	var errs ValidationErrors
+
+	for _, opt := range spec.Cmd().Opts {
+		v := reflect.ValueOf(opt.ref(false))
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			continue
+		}
+		// Unset options are only checked if they're required,
+		// but unknown rules are always reported.
+		check := opt.IsSet || hasRule(opt.Rules, "required")
+		for _, rule := range opt.Rules {
+			if !check && ruleNames[rule.Name] {
+				continue
+			}
+			err := checkRule(rule, v.Elem(), opt.Sensitive)
+			if err != nil {
+				errs = append(errs, &ValidationError{
+					Key:    opt.Key,
+					Source: opt.Source,
+					Err:    err,
+				})
+				if rule.Name == "required" {
+					// The other rules would fail for the same reason.
+					break
+				}
+			}
+		}
+		if check {
+			validateElems(opt, v.Elem(), &errs)
+		}
+	}
+
+	for _, arg := range spec.Cmd().Args {
+		v := reflect.ValueOf(arg.Value)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			continue
+		}
+		for _, rule := range arg.Rules {
+			err := checkRule(rule, v.Elem(), false)
+			if err != nil {
+				errs = append(errs, &ValidationError{
+					Key: []string{arg.Name},
+					Err: err,
+				})
+			}
+		}
+	}
+
+	if ospec, ok := spec.(OptSpec); ok {
+		callValidators(reflect.ValueOf(ospec.Opt()), nil, &errs)
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// hasRule returns true if the rules include a rule with the given name.
+func hasRule(rules []Rule, name string) bool {
+	for _, r := range rules {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setFlags sets the Flag of validation errors to the name of the flag
+// which sets the option, if the loader has a flags provider.
+func setFlags(err error, l *Loader) {
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		return
+	}
+	for _, p := range l.providers {
+		pf, ok := p.(*pflags)
+		if !ok {
+			continue
+		}
+		for _, e := range errs {
+			if len(e.Key) == 0 || e.Flag != "" {
+				continue
+			}
+			if name := pf.keyfunc(e.Key); pf.Lookup(name) != nil {
+				e.Flag = "--" + name
+			}
+		}
+	}
+}
+
+// validateElems checks the field rules of each element of a list of structs.
+func validateElems(opt *Opt, list reflect.Value, errs *ValidationErrors) {
+	if len(opt.Fields) == 0 || list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return
+	}
+	for i := 0; i < list.Len(); i++ {
+		for _, f := range opt.Fields {
+			v, ok := fieldByKey(list.Index(i), f.Key)
+			if !ok {
+				continue
+			}
+			for _, rule := range f.Rules {
+				err := checkRule(rule, v, opt.Sensitive || f.Sensitive)
+				if err != nil {
+					*errs = append(*errs, &ValidationError{
+						Key:    elemKey(opt.Key, i, f.Key),
+						Source: opt.Source,
+						Err:    err,
+					})
+				}
+			}
+		}
+	}
+}
+
+// fieldByKey returns the struct field at the given key, following pointers.
+// Returns false if a pointer along the way is nil.
+func fieldByKey(v reflect.Value, key []string) (reflect.Value, bool) {
+	for _, k := range key {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		v = v.FieldByName(k)
+		if !v.IsValid() {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// callValidators walks a struct value, calling Validate on any value
+// which implements Validator.
+func callValidators(v reflect.Value, key []string, errs *ValidationErrors) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		// Check the pointer's method set, then walk the struct it points to.
+		if v.Type().Implements(validatorType) {
+			if err := v.Interface().(Validator).Validate(); err != nil {
+				*errs = append(*errs, &ValidationError{Key: key, Err: err})
+			}
+		}
+		elem := v.Elem()
+		if elem.Kind() == reflect.Struct {
+			walkFields(elem, key, errs)
+		}
+
+	case reflect.Struct:
+		if v.CanAddr() {
+			callValidators(v.Addr(), key, errs)
+			return
+		}
+		if v.Type().Implements(validatorType) {
+			if err := v.Interface().(Validator).Validate(); err != nil {
+				*errs = append(*errs, &ValidationError{Key: key, Err: err})
+			}
+		}
+		walkFields(v, key, errs)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			callValidators(v.Index(i), newKey(key, strconv.Itoa(i)), errs)
+		}
+	}
+}
+
+func walkFields(v reflect.Value, key []string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		sub := key
+		if !f.Anonymous {
+			sub = newKey(key, f.Name)
+		}
+		callValidators(v.Field(i), sub, errs)
+	}
+}
+
+// newKey copies a key and appends a part.
+func newKey(key []string, add ...string) []string {
+	k := append([]string{}, key...)
+	return append(k, add...)
+}
+
+// ruleNames contains the names of the built-in rules.
+var ruleNames = map[string]bool{
+	"required": true,
+	"min":      true,
+	"max":      true,
+	"oneof":    true,
+	"regexp":   true,
+	"file":     true,
+	"dir":      true,
+	"port":     true,
+}
+
+// checkRule checks a value against a single validation rule.
+func checkRule(r Rule, v reflect.Value, sensitive bool) error {
+	// Check the name first, so that a typo is reported
+	// even if the value is empty.
+	if !ruleNames[r.Name] {
+		return fmt.Errorf("unknown validation rule %q", r.Name)
+	}
+
+	if r.Name == "required" {
+		if isZero(v) {
+			return fmt.Errorf("a value is required")
+		}
+		return nil
+	}
+
+	// Pointer options, e.g. *int, are checked by the value they point to.
+	// A nil pointer is unset, so only "required" applies.
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	// Rules on strings and lengths don't apply to empty values,
+	// use "required" for that. Numbers are checked even when zero,
+	// e.g. zero isn't a valid port.
+	numeric := r.Name == "min" || r.Name == "max" || r.Name == "port"
+	if isZero(v) && !(numeric && isNumber(v)) {
+		return nil
+	}
+
+	switch r.Name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(r.Arg, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s rule %q", r.Name, r.Arg)
+		}
+		n, what, ok := measure(v)
+		if !ok {
+			return fmt.Errorf("%s rule doesn't apply to type %s", r.Name, v.Type())
+		}
+		if r.Name == "min" && n < limit {
+			return fmt.Errorf("%s must be at least %s", what, r.Arg)
+		}
+		if r.Name == "max" && n > limit {
+			return fmt.Errorf("%s must be at most %s", what, r.Arg)
+		}
+
+	case "oneof":
+		allowed := strings.Fields(r.Arg)
+		for _, s := range stringValues(v) {
+			if !contains(allowed, s) {
+				return fmt.Errorf("must be one of: %s", strings.Join(allowed, ", "))
+			}
+		}
+
+	case "regexp":
+		re, err := regexp.Compile(r.Arg)
+		if err != nil {
+			return fmt.Errorf("invalid regexp rule %q: %v", r.Arg, err)
+		}
+		for _, s := range stringValues(v) {
+			if !re.MatchString(s) {
+				return fmt.Errorf("must match the regular expression %q", r.Arg)
+			}
+		}
+
+	case "file", "dir":
+		for _, path := range stringValues(v) {
+			info, err := os.Stat(path)
+			if sensitive {
+				path = Redacted
+			}
+			switch {
+			case err != nil:
+				return fmt.Errorf("%s %q doesn't exist", r.Name, path)
+			case r.Name == "file" && info.IsDir():
+				return fmt.Errorf("%q is a directory, not a file", path)
+			case r.Name == "dir" && !info.IsDir():
+				return fmt.Errorf("%q is not a directory", path)
+			}
+		}
+
+	case "port":
+		n, _, ok := measure(v)
+		if !ok || v.Kind() == reflect.String {
+			return fmt.Errorf("port rule doesn't apply to type %s", v.Type())
+		}
+		if n < 1 || n > 65535 {
+			return fmt.Errorf("must be a valid port number (1-65535)")
+		}
+
+	}
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func isNumber(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// measure returns a number for comparing a value against min/max rules:
+// the value of a number, or the length of a string, slice, or map.
+func measure(v reflect.Value) (n float64, what string, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "value", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "value", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "value", true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), "length", true
+	}
+	return 0, "", false
+}
+
+// stringValues returns the string form of a value, or of each element
+// of a slice, for rules which check strings.
+func stringValues(v reflect.Value) []string {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var s []string
+		for i := 0; i < v.Len(); i++ {
+			s = append(s, fmt.Sprint(v.Index(i).Interface()))
+		}
+		return s
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
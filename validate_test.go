@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/pflag"
+	"os"
+	"reflect"
+	"testing"
+)
+
+type validateOpt struct {
+	Port    int
+	Workers int
+	Level   string
+	Tags    []string
+	DB      validateDB
+}
+
+type validateDB struct {
+	User, Password string
+}
+
+func (db validateDB) Validate() error {
+	if db.Password != "" && db.User == "" {
+		return errors.New("a user is required when a password is given")
+	}
+	return nil
+}
+
+type validateSpec struct {
+	cmd *Cmd
+	opt validateOpt
+}
+
+func (v *validateSpec) Run(ctx context.Context) (interface{}, error) { return nil, nil }
+
+func (v *validateSpec) Opt() interface{} {
+	return &v.opt
+}
+
+func (v *validateSpec) Cmd() *Cmd {
+	if v.cmd != nil {
+		return v.cmd
+	}
+	v.cmd = &Cmd{
+		RawName: "Run",
+		Opts: []*Opt{
+			{Key: []string{"Port"}, Value: &v.opt.Port, Tag: `validate:"required,port"`},
+			{Key: []string{"Workers"}, Value: &v.opt.Workers, Tag: `validate:"min=1"`},
+			{Key: []string{"Level"}, Value: &v.opt.Level, RawDoc: "Log level.\nValidate: oneof=debug info error"},
+			{Key: []string{"Tags"}, Value: &v.opt.Tags, Tag: `validate:"max=2,regexp=^[a-z,]+$"`},
+			{Key: []string{"DB", "User"}, Value: &v.opt.DB.User},
+			{Key: []string{"DB", "Password"}, Value: &v.opt.DB.Password},
+		},
+	}
+	Enrich(v.cmd)
+	return v.cmd
+}
+
+func TestParseRules(t *testing.T) {
+	got := parseRules("required, min=1,regexp=^a,b$")
+	expect := []Rule{
+		{Name: "required"},
+		{Name: "min", Arg: "1"},
+		{Name: "regexp", Arg: "^a,b$"},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %v, got %v", expect, got)
+	}
+}
+
+func TestCheckRuleZero(t *testing.T) {
+	var nilPort *int
+	tests := []struct {
+		rule  string
+		value interface{}
+		ok    bool
+	}{
+		// Numbers are checked even when zero.
+		{"min=1", 0, false},
+		{"max=-1", 0.0, false},
+		{"port", 0, false},
+		{"port", uint16(0), false},
+		{"min=0", 0, true},
+		// Empty strings and lists are only checked by required.
+		{"min=1", "", true},
+		{"oneof=a b", "", true},
+		{"regexp=^a$", "", true},
+		{"file", "", true},
+		{"dir", []string{}, true},
+		{"required", "", false},
+		// A nil pointer is unset.
+		{"port", nilPort, true},
+		// Unknown rules are reported for zero values too.
+		{"requird", 0, false},
+		{"mni=1", "", false},
+	}
+	for _, test := range tests {
+		r := parseRules(test.rule)[0]
+		err := checkRule(r, reflect.ValueOf(test.value), false)
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("%s on %#v: expected ok=%v, got %v", test.rule, test.value, test.ok, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	spec := &validateSpec{opt: validateOpt{Port: 8080, Level: "info"}}
+	if err := Validate(spec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spec = &validateSpec{}
+	env := map[string]string{
+		"APP_PORT":        "70000",
+		"APP_LEVEL":       "trace",
+		"APP_TAGS":        "a,b,c",
+		"APP_DB_PASSWORD": "secret",
+	}
+	l := NewLoader(spec.Cmd().Opts, EnvWith(EnvOpts{
+		Prefix: "app",
+		LookupEnv: func(k string) (string, bool) {
+			v, ok := env[k]
+			return v, ok
+		},
+	}))
+	l.Load()
+	if errs := l.Errors(); errs != nil {
+		t.Fatal(errs)
+	}
+
+	err := Validate(spec)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %#v", err)
+	}
+	if len(errs) != 4 {
+		t.Errorf("expected 4 errors, got %d:\n%s", len(errs), errs)
+	}
+}
+
+func ExampleValidate() {
+	spec := &validateSpec{}
+	opts := spec.Cmd().Opts
+	l := NewLoader(opts, Env("app"))
+
+	os.Setenv("APP_PORT", "70000")
+	os.Setenv("APP_LEVEL", "trace")
+	defer os.Unsetenv("APP_PORT")
+	defer os.Unsetenv("APP_LEVEL")
+	spec.opt.DB.Password = "secret"
+	l.Load()
+
+	fmt.Println(Validate(spec))
+
+	// Output:
+	// invalid port (env APP_PORT): must be a valid port number (1-65535)
+	// invalid level (env APP_LEVEL): must be one of: debug, info, error
+	// invalid db: a user is required when a password is given
+}
+
+func TestValidateUnset(t *testing.T) {
+	tests := []struct {
+		args   []string
+		expect string
+	}{
+		// Workers is optional, so its min rule only applies when it's set.
+		{nil, "invalid --port: a value is required"},
+		{[]string{"--port", "80"}, ""},
+		{[]string{"--port", "80", "--workers", "0"}, "invalid workers (flags --workers): value must be at least 1"},
+	}
+	for _, test := range tests {
+		spec := &validateSpec{}
+		opts := spec.Cmd().Opts
+		fs := pflag.NewFlagSet("validate", pflag.ContinueOnError)
+		l := NewLoader(opts, PFlags(fs, opts, DotKey))
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := RunContext(context.Background(), spec, l, nil)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.expect {
+			t.Errorf("%v: expected error %q, got %q", test.args, test.expect, got)
+		}
+	}
+}
+
+func TestValidateElems(t *testing.T) {
+	backends := []testBackend{{Addr: "a:80"}, {MaxWeight: 20}}
+	opt := testBackendOpt(&backends)
+	opt.IsSet = true
+	spec := &testSpec{cmd: &Cmd{Opts: []*Opt{opt}}}
+
+	err := Validate(spec)
+	expect := "invalid backends.1.addr: a value is required\n" +
+		"invalid backends.1.maxweight: value must be at most 10"
+	if err == nil || err.Error() != expect {
+		t.Errorf("expected errors:\n%s\ngot:\n%v", expect, err)
+	}
+}